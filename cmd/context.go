@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// timeout limits the duration of a run; 0 means no limit
+	timeout time.Duration
+	// cancelTimeout releases the timer of the timeout
+	cancelTimeout context.CancelFunc = func() {}
+)
+
+func init() {
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "abort if the run takes longer than this, e.g. 30m (0 means no limit)")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if timeout < 0 {
+			return fmt.Errorf("invalid timeout %s", timeout)
+		}
+		if timeout > 0 {
+			var ctx context.Context
+			ctx, cancelTimeout = context.WithTimeout(cmd.Context(), timeout)
+			cmd.SetContext(ctx)
+		}
+		return nil
+	}
+}
+
+// signalContext returns a context cancelled on SIGINT or SIGTERM. The signals stay caught until
+// stop is called, so a second Ctrl-C cannot kill the process while it copies files into the
+// target directory.
+func signalContext() (ctx context.Context, stop context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// contextError explains an error caused by the run being interrupted or timing out
+func contextError(err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("timed out after %s: %w", timeout, err)
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("interrupted: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/doctor"
+	"github.com/SKevo18/mediawiki-updater/internal/mediawiki"
+	"github.com/SKevo18/mediawiki-updater/internal/php"
+	"github.com/SKevo18/mediawiki-updater/internal/updater"
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd checks the host for the configured MediaWiki release
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that the host can run the configured MediaWiki release",
+	Long: `Check the host before an update.
+
+The PHP binary is run to list its version and loaded extensions, which are
+compared against the composer.json of the configured MediaWiki release (or a
+built-in table of minimum PHP versions if it cannot be fetched). The free disk
+space and write permissions of the target directory are checked as well.
+
+The same checks run automatically during an update, before anything is replaced.
+Exits with a non-zero status if any check fails.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDoctor(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(ctx context.Context) error {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return err
+	}
+	if profile != "" {
+		if cfg, err = cfg.WithProfile(profile); err != nil {
+			return err
+		}
+	}
+
+	sources, client, err := prepareNetwork(cfg)
+	if err != nil {
+		return err
+	}
+
+	absTargetDir, err := filepath.Abs(targetDir)
+	if err != nil {
+		return fmt.Errorf("invalid target directory: %w", err)
+	}
+
+	mwVersion := cfg.MediaWiki.Version
+	fmt.Printf("Checking %s for MediaWiki %s...\n", absTargetDir, mwVersion)
+
+	opts := doctor.Options{
+		TargetDir:     absTargetDir,
+		PHPBinary:     phpBinary,
+		MediaWiki:     mwVersion,
+		RequiredSpace: doctor.DefaultRequiredSpace,
+	}
+	if opts.PHPBinary == "" {
+		opts.PHPBinary = cfg.MediaWiki.PHP
+	}
+	opts.PHPRequired = opts.PHPBinary != ""
+	if opts.PHPBinary == "" {
+		opts.PHPBinary = php.DefaultBinary
+	}
+
+	composerJSON, err := mediawiki.NewParser(updater.ParserOptions(sources, client)).FetchComposerJSON(ctx, mwVersion)
+	if err != nil {
+		fmt.Printf("  Using built-in PHP requirements: %v\n", err)
+	}
+	if core, err := doctor.CoreRequirements(mwVersion, composerJSON); err != nil {
+		fmt.Printf("  %v\n", err)
+	} else {
+		opts.Requirements = append(opts.Requirements, core)
+	}
+
+	results := doctor.Run(ctx, opts)
+	doctor.Print(os.Stdout, results)
+
+	if doctor.Failed(results) {
+		return fmt.Errorf("%s cannot run MediaWiki %s", absTargetDir, mwVersion)
+	}
+
+	fmt.Println("All checks passed.")
+	return nil
+}
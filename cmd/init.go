@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SKevo18/mediawiki-updater/internal/detect"
+	"github.com/spf13/cobra"
+)
+
+var initForce bool
+
+// initCmd generates a configuration file from an existing installation
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Generate a configuration file from an existing MediaWiki installation",
+	Long: `Inspect the MediaWiki installation in the target directory and write a
+configuration file for it.
+
+The MediaWiki version is read from includes/Defines.php. Every directory in
+extensions/ and skins/ is listed with the name and version from its
+extension.json or skin.json: Git checkouts become git= entries pinned to their
+current branch or commit, everything else becomes an extdist= entry. Components
+that LocalSettings.php does not load are written commented out.
+
+The configuration is written to the --config path; use "-" to print it instead.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runInit()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().BoolVar(&initForce, "force", false, "overwrite an existing configuration file")
+}
+
+func runInit() error {
+	absTargetDir, err := filepath.Abs(targetDir)
+	if err != nil {
+		return fmt.Errorf("invalid target directory: %w", err)
+	}
+
+	installation, err := detect.Detect(absTargetDir)
+	if err != nil {
+		return err
+	}
+
+	if configFile == "-" {
+		return installation.WriteINI(os.Stdout)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if initForce {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(configFile, flags, 0o644)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s already exists (use --force to overwrite it)", configFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create configuration file: %w", err)
+	}
+	defer file.Close()
+
+	if err := installation.WriteINI(file); err != nil {
+		return fmt.Errorf("failed to write configuration file: %w", err)
+	}
+
+	fmt.Printf("Wrote %s for MediaWiki %s (%d extensions, %d skins)\n", configFile, installation.Version, len(installation.Extensions), len(installation.Skins))
+	for _, missing := range installation.Missing {
+		fmt.Printf("Warning: LocalSettings.php loads %s, which is not installed\n", missing)
+	}
+	return file.Close()
+}
@@ -1,12 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
 
-	"github.com/PuerkitoBio/goquery"
-	"github.com/SKevo18/mediawiki-updater/internal/httputil"
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/extdist"
+	"github.com/SKevo18/mediawiki-updater/internal/mediawiki"
+	"github.com/SKevo18/mediawiki-updater/internal/updater"
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +21,7 @@ var listCmd = &cobra.Command{
 
 Available subcommands:
 - versions: List available MediaWiki versions
-- extensions: List available extensions from ExtDist  
+- extensions: List available extensions from ExtDist
 - skins: List available skins from ExtDist`,
 }
 
@@ -27,25 +30,25 @@ var versionsCmd = &cobra.Command{
 	Use:   "versions",
 	Short: "List available MediaWiki versions",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return listMediaWikiVersions()
+		return listMediaWikiVersions(cmd.Context())
 	},
 }
 
 // extensionsCmd lists available extensions
 var extensionsCmd = &cobra.Command{
-	Use:   "extensions",
-	Short: "List available extensions from ExtDist",
+	Use:   "extensions [name...]",
+	Short: "List available extensions from ExtDist, or the branches of the named extensions",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return listComponents("https://extdist.wmflabs.org/dist/extensions/", "Extensions")
+		return listComponents(cmd.Context(), config.TypeExtension, args)
 	},
 }
 
 // skinsCmd lists available skins
 var skinsCmd = &cobra.Command{
-	Use:   "skins",
-	Short: "List available skins from ExtDist",
+	Use:   "skins [name...]",
+	Short: "List available skins from ExtDist, or the branches of the named skins",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return listComponents("https://extdist.wmflabs.org/dist/skins/", "Skins")
+		return listComponents(cmd.Context(), config.TypeSkin, args)
 	},
 }
 
@@ -56,35 +59,19 @@ func init() {
 	listCmd.AddCommand(skinsCmd)
 }
 
-func listMediaWikiVersions() error {
+func listMediaWikiVersions(ctx context.Context) error {
 	fmt.Println("Fetching available MediaWiki versions...")
 
-	// Get the main releases page
-	resp, err := httputil.Get("https://releases.wikimedia.org/mediawiki/")
+	sources, client, err := prepareNetwork(nil)
 	if err != nil {
-		return fmt.Errorf("failed to fetch releases page: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	versions, err := mediawiki.NewParser(updater.ParserOptions(sources, client)).ListVersionSeries(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to parse releases page: %w", err)
+		return err
 	}
 
-	var versions []string
-	doc.Find("a").Each(func(i int, s *goquery.Selection) {
-		href := s.AttrOr("href", "")
-		// Look for version directories (e.g., "1.43/")
-		if strings.Contains(href, ".") && strings.HasSuffix(href, "/") {
-			version := strings.TrimSuffix(href, "/")
-			if strings.Count(version, ".") == 1 { // Only major.minor versions
-				versions = append(versions, version)
-			}
-		}
-	})
-
-	sort.Strings(versions)
-
 	fmt.Printf("\nAvailable MediaWiki version series:\n")
 	for _, version := range versions {
 		fmt.Printf("- %s\n", version)
@@ -93,54 +80,52 @@ func listMediaWikiVersions() error {
 	return nil
 }
 
-func listComponents(url, componentType string) error {
-	fmt.Printf("Fetching available %s from ExtDist...\n", strings.ToLower(componentType))
-
-	resp, err := httputil.Get(url)
-	if err != nil {
-		return fmt.Errorf("failed to fetch %s page: %w", strings.ToLower(componentType), err)
-	}
-	defer resp.Body.Close()
+func listComponents(ctx context.Context, componentType string, names []string) error {
+	label := componentType + "s"
+	fmt.Printf("Fetching available %s from ExtDist...\n", label)
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	sources, httpClient, err := prepareNetwork(nil)
 	if err != nil {
-		return fmt.Errorf("failed to parse %s page: %w", strings.ToLower(componentType), err)
+		return err
 	}
+	client := extdist.NewClient(extdist.Options{APIURLs: sources.ExtDistAPI, IndexURLs: sources.ExtDist, HTTPClient: httpClient, Logger: logger()})
 
-	componentMap := make(map[string][]string)
-
-	doc.Find("a").Each(func(i int, s *goquery.Selection) {
-		href := s.AttrOr("href", "")
-		if strings.HasSuffix(href, ".tar.gz") {
-			// Extract component name and version
-			filename := strings.TrimSuffix(href, ".tar.gz")
-			parts := strings.Split(filename, "-")
-			if len(parts) >= 2 {
-				// First part is component name, rest is version
-				componentName := parts[0]
-				version := strings.Join(parts[1:], "-")
-				componentMap[componentName] = append(componentMap[componentName], version)
-			}
+	if len(names) == 0 {
+		available, err := client.Repositories(ctx, componentType)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", label, err)
+		}
+		if len(available) == 0 {
+			fmt.Printf("No %s found.\n", label)
+			return nil
 		}
-	})
 
-	if len(componentMap) == 0 {
-		fmt.Printf("No %s found.\n", strings.ToLower(componentType))
+		fmt.Printf("\nAvailable %s:\n", label)
+		for _, name := range available {
+			fmt.Printf("- %s\n", name)
+		}
 		return nil
 	}
 
-	// Sort component names
-	var componentNames []string
-	for name := range componentMap {
-		componentNames = append(componentNames, name)
+	branches, err := client.Branches(ctx, componentType, names...)
+	if err != nil {
+		return fmt.Errorf("failed to look up %s: %w", label, err)
 	}
-	sort.Strings(componentNames)
 
-	fmt.Printf("\nAvailable %s:\n", componentType)
-	for _, name := range componentNames {
-		versions := componentMap[name]
-		sort.Strings(versions)
-		fmt.Printf("- %s (versions: %s)\n", name, strings.Join(versions, ", "))
+	fmt.Println()
+	for _, name := range names {
+		archives, ok := branches[name]
+		if !ok {
+			fmt.Printf("- %s (not found)\n", name)
+			continue
+		}
+
+		var available []string
+		for branch, archive := range archives {
+			available = append(available, branch+" @ "+archive.Hash)
+		}
+		sort.Strings(available)
+		fmt.Printf("- %s (branches: %s)\n", name, strings.Join(available, ", "))
 	}
 
 	return nil
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/SKevo18/mediawiki-updater/internal/updater"
+	"github.com/spf13/cobra"
+)
+
+var mirrorOut string
+
+// mirrorCmd downloads everything an update needs into an offline bundle
+var mirrorCmd = &cobra.Command{
+	Use:   "mirror",
+	Short: "Download MediaWiki core and all components into an offline bundle",
+	Long: `Resolve the configuration and download MediaWiki core and every extension
+and skin into a bundle directory, for hosts without network access.
+
+The bundle holds the MediaWiki release archive and a tarball of every component
+(Git components are archived at their resolved commit), and a manifest.json
+with the SHA-256 checksum of every file. Copy the directory to the offline host
+and install from it with:
+
+  mediawiki-updater --config config.ini --target /var/www/mediawiki --from-bundle bundle/`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMirror(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mirrorCmd)
+	mirrorCmd.Flags().StringVarP(&mirrorOut, "out", "o", "bundle", "directory to write the bundle to")
+	mirrorCmd.Flags().BoolVar(&withDeps, "with-dependencies", false, "also bundle the extensions and skins required by the configured components")
+}
+
+func runMirror(ctx context.Context) error {
+	updaterInstance, err := updater.NewUpdater(updater.Options{
+		ConfigPath:   configFile,
+		UseGitBinary: gitBinary,
+		Profile:      profile,
+		Sources:      sourceFlags,
+		HTTP:         httpFlags,
+		Progress:     progressReporter(),
+		Logger:       logger(),
+
+		WithDependencies: withDeps,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Building bundle in %s...\n", mirrorOut)
+	mirrorErr := updaterInstance.Mirror(ctx, mirrorOut)
+	updaterInstance.Report().Print(os.Stdout)
+	return mirrorErr
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/httputil"
+	"github.com/SKevo18/mediawiki-updater/internal/updater"
+)
+
+var (
+	// sourceFlags are the mirrors given on the command line, overriding the [sources] section
+	sourceFlags config.SourcesConfig
+	// httpFlags are the HTTP client settings given on the command line, overriding the [http] section
+	httpFlags config.HTTPConfig
+)
+
+func init() {
+	flags := rootCmd.PersistentFlags()
+	flags.StringSliceVar(&sourceFlags.Releases, "releases-url", nil, "mirrors of the MediaWiki release directory, tried in order")
+	flags.StringSliceVar(&sourceFlags.ExtDist, "extdist-url", nil, "mirrors of the ExtDist archive directories, tried in order")
+	flags.StringSliceVar(&sourceFlags.ExtDistAPI, "extdist-api-url", nil, `MediaWiki APIs serving ExtDist metadata, tried in order ("none" to only use the archive directories)`)
+	flags.StringSliceVar(&sourceFlags.Composer, "composer-url", nil, "mirrors serving <version>/composer.json of MediaWiki releases, tried in order")
+
+	flags.StringVar(&httpFlags.Proxy, "proxy", "", "proxy URL for all HTTP(S) requests (default: HTTP_PROXY and HTTPS_PROXY)")
+	flags.StringSliceVar(&httpFlags.NoProxy, "no-proxy", nil, "hosts, domains and CIDR ranges that bypass the proxy (default: NO_PROXY)")
+	flags.StringSliceVar(&httpFlags.CAFiles, "ca-file", nil, "PEM files with certificate authorities to trust in addition to the system ones")
+	flags.StringVar(&httpFlags.ClientCert, "client-cert", "", "PEM client certificate for servers that require mutual TLS")
+	flags.StringVar(&httpFlags.ClientKey, "client-key", "", "PEM private key of the client certificate")
+	flags.StringVar(&httpFlags.UserAgentSuffix, "user-agent-suffix", "", "text appended to the User-Agent header")
+	flags.Float64Var(&httpFlags.RateLimit, "rate-limit", 0, "requests per second to every host (default 5, negative for no limit)")
+	flags.IntVar(&httpFlags.MaxConcurrent, "max-concurrent", 0, "requests in flight at once (default 4, negative for no cap)")
+}
+
+// prepareNetwork returns the mirrors of the [sources] section of cfg and an HTTP client with the
+// settings of its [http] section, both overridden by the command line. If cfg is nil, the
+// configuration file is read if it exists.
+func prepareNetwork(cfg *config.Config) (config.SourcesConfig, *httputil.Client, error) {
+	var sources config.SourcesConfig
+	if cfg == nil {
+		if _, err := os.Stat(configFile); err == nil {
+			loaded, err := config.LoadConfig(configFile)
+			if err != nil {
+				return sources, nil, err
+			}
+			cfg = loaded
+		}
+	}
+
+	settings := httpFlags
+	if cfg != nil {
+		sources = cfg.Sources
+		settings = cfg.HTTP.Override(httpFlags)
+	}
+
+	sources = sources.Override(sourceFlags)
+	if err := sources.Check(); err != nil {
+		return sources, nil, err
+	}
+
+	client, err := updater.NewHTTPClient(settings, sources, logger())
+	return sources, client, err
+}
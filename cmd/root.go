@@ -1,10 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime/debug"
+	"time"
 
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+	"github.com/SKevo18/mediawiki-updater/internal/progress"
 	"github.com/SKevo18/mediawiki-updater/internal/updater"
 	"github.com/spf13/cobra"
 )
@@ -13,6 +18,16 @@ var (
 	configFile string
 	targetDir  string
 	verbose    bool
+	reportFile string
+	gitBinary  bool
+	profile    string
+	strict     bool
+	phpBinary  string
+	withDeps   bool
+	fromBundle string
+	noProgress bool
+
+	ignorePlatformReqs bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -20,32 +35,65 @@ var rootCmd = &cobra.Command{
 	Use:   "mediawiki-updater",
 	Short: "A tool to download and update MediaWiki core, extensions, and skins",
 	Long: `MediaWiki Updater is a CLI tool that downloads and installs MediaWiki core 
-along with configured extensions and skins based on an INI configuration file.
+along with configured extensions and skins based on an INI, YAML, TOML or JSON
+configuration file.
 
 The tool supports:
 - Downloading MediaWiki core from official releases
 - Installing extensions and skins from ExtDist or Git repositories
 - Configurable version management
 - Preserving specified files during updates`,
+	// Errors are printed by Execute
+	SilenceErrors: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runUpdate()
+		return runUpdate(cmd.Context())
 	},
 }
 
-func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+// Execute runs the command line. version is the release the binary was built from, set with
+// -ldflags "-X main.version=v1.2.3"; if it is empty, the module version of the build is used.
+func Execute(version string) {
+	rootCmd.Version = buildVersion(version)
+
+	ctx, stop := signalContext()
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	stop()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", contextError(err))
 		os.Exit(1)
 	}
 }
 
+// buildVersion returns version, or the version of the module if the binary was built with
+// go install from a tagged release, or "dev"
+func buildVersion(version string) string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "config.ini", "path to configuration file")
 	rootCmd.PersistentFlags().StringVarP(&targetDir, "target", "t", ".", "target directory for MediaWiki installation")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose output")
+	rootCmd.PersistentFlags().BoolVar(&gitBinary, "git-binary", false, "fetch git components with the git binary instead of the built-in implementation")
+	rootCmd.PersistentFlags().StringVarP(&profile, "profile", "p", "", "configuration profile to merge onto the base configuration")
+	rootCmd.PersistentFlags().BoolVar(&noProgress, "no-progress", false, "do not report the progress of downloads")
+	rootCmd.PersistentFlags().StringVar(&phpBinary, "php", "", "PHP binary used to check requirements (default: php setting of the configuration, or php from the PATH)")
+	rootCmd.Flags().BoolVar(&ignorePlatformReqs, "ignore-platform-reqs", false, "update even if the PHP, disk space or permission checks fail")
+	rootCmd.Flags().BoolVar(&strict, "strict", false, "abort before anything is replaced if a component's requirements are not met")
+	rootCmd.Flags().BoolVar(&withDeps, "with-dependencies", false, "also download the extensions and skins required by the configured components")
+	rootCmd.Flags().StringVar(&reportFile, "report", "", "write a JSON run report to this file")
+	rootCmd.Flags().StringVar(&fromBundle, "from-bundle", "", "install from a bundle written by the mirror command instead of downloading")
 }
 
-func runUpdate() error {
+func runUpdate(ctx context.Context) error {
 	// Validate target directory
 	absTargetDir, err := filepath.Abs(targetDir)
 	if err != nil {
@@ -65,12 +113,27 @@ func runUpdate() error {
 	if verbose {
 		fmt.Printf("Using configuration file: %s\n", configFile)
 		fmt.Printf("Target directory: %s\n", absTargetDir)
+		if profile != "" {
+			fmt.Printf("Profile: %s\n", profile)
+		}
 	}
 
 	// Create updater instance
 	opts := updater.Options{
-		ConfigPath: configFile,
-		TargetDir:  absTargetDir,
+		ConfigPath:   configFile,
+		TargetDir:    absTargetDir,
+		UseGitBinary: gitBinary,
+		Profile:      profile,
+		Strict:       strict,
+		PHPBinary:    phpBinary,
+		Bundle:       fromBundle,
+
+		Sources:            sourceFlags,
+		HTTP:               httpFlags,
+		Progress:           progressReporter(),
+		Logger:             logger(),
+		WithDependencies:   withDeps,
+		IgnorePlatformReqs: ignorePlatformReqs,
 	}
 
 	updaterInstance, err := updater.NewUpdater(opts)
@@ -80,10 +143,37 @@ func runUpdate() error {
 
 	// Perform update
 	fmt.Println("Starting MediaWiki update process...")
-	if err := updaterInstance.Update(absTargetDir); err != nil {
-		return err
+	updateErr := updaterInstance.Update(ctx, absTargetDir)
+
+	report := updaterInstance.Report()
+	report.Print(os.Stdout)
+	if reportFile != "" {
+		if err := report.WriteFile(reportFile); err != nil {
+			return err
+		}
+	}
+
+	if updateErr != nil {
+		return updateErr
 	}
 
 	fmt.Println("MediaWiki update completed successfully!")
 	return nil
 }
+
+// progressReporter returns the reporter of download progress: bars on a terminal, and a line
+// every few seconds in the logs of non-interactive runs
+func progressReporter() progress.Reporter {
+	if noProgress {
+		return progress.Nop
+	}
+	return progress.Auto(os.Stdout, 5*time.Second)
+}
+
+// logger returns the logger of the command line, which also prints debug messages with --verbose
+func logger() logging.Logger {
+	if verbose {
+		return logging.Verbose
+	}
+	return logging.Stdout
+}
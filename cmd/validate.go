@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SKevo18/mediawiki-updater/internal/downloader"
+	"github.com/SKevo18/mediawiki-updater/internal/updater"
+	"github.com/SKevo18/mediawiki-updater/internal/validate"
+	"github.com/spf13/cobra"
+)
+
+var validateOnline bool
+
+// validateCmd checks the configuration file for problems
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration file for problems",
+	Long: `Parse the configuration file and report problems with their line numbers.
+
+Offline checks flag unknown sections and keys, unknown distributors, malformed
+versions, duplicate components and malformed Git URLs. With --online, every
+ExtDist component is also looked up for its REL branch and every Git reference
+is resolved against its repository.
+
+Exits with a non-zero status if any problem is found.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runValidate(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().BoolVar(&validateOnline, "online", false, "also check that every component can be resolved from its distributor")
+}
+
+func runValidate(ctx context.Context) error {
+	cfg, problems := validate.Offline(configFile, profile)
+
+	if cfg != nil && validateOnline {
+		sources, client, err := prepareNetwork(cfg)
+		if err != nil {
+			return err
+		}
+		opts := updater.DownloaderOptions(sources, gitBinary, client)
+		opts.Logger = logger()
+		d, err := downloader.NewDownloader(opts)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, validate.Online(ctx, cfg, d, logger())...)
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problem(s) in %s", len(problems), configFile)
+	}
+
+	fmt.Printf("%s is valid (%d extensions, %d skins)\n", configFile, len(cfg.Extensions), len(cfg.Skins))
+	return nil
+}
@@ -0,0 +1,236 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ManifestFile is the name of the manifest in the bundle directory
+const ManifestFile = "manifest.json"
+
+// Format is the version of the manifest format written by this package
+const Format = 1
+
+// Manifest lists the artifacts of an offline bundle: a directory with the MediaWiki core archive
+// and a tarball of every extension and skin, each with its SHA-256 checksum
+type Manifest struct {
+	Format     int         `json:"format"`
+	Created    time.Time   `json:"created"`
+	MediaWiki  Core        `json:"mediawiki"`
+	Components []Component `json:"components"`
+}
+
+// Artifact is a file in the bundle
+type Artifact struct {
+	File   string `json:"file"` // path relative to the bundle directory, with forward slashes
+	SHA256 string `json:"sha256"`
+}
+
+// Core is the MediaWiki release archive in the bundle
+type Core struct {
+	Version string `json:"version"`
+	URL     string `json:"url,omitempty"` // URL the archive was downloaded from
+	Artifact
+}
+
+// Component is a tarball of an extension or skin in the bundle. The tarball holds a single
+// top-level directory with the component files.
+type Component struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Distributor string `json:"distributor"`
+	Dir         string `json:"dir"` // installation directory relative to the MediaWiki root, e.g. "extensions/Math"
+	Version     string `json:"version,omitempty"`
+	// RequestedVersion is the ExtDist branch that was requested if a fallback branch was bundled instead
+	RequestedVersion string `json:"requested_version,omitempty"`
+	Commit           string `json:"commit,omitempty"`
+	URL              string `json:"url,omitempty"`
+	// RequiredBy lists the components that caused a dependency to be bundled, e.g. "extension/VisualEditor"
+	RequiredBy []string `json:"required_by,omitempty"`
+	Artifact
+}
+
+// Read reads the manifest of the bundle in dir
+func Read(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	if m.Format != Format {
+		return nil, fmt.Errorf("unsupported bundle format %d (expected %d)", m.Format, Format)
+	}
+	return &m, nil
+}
+
+// Write writes the manifest into the bundle directory
+func (m *Manifest) Write(dir string) error {
+	m.Format = Format
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write bundle manifest: %w", err)
+	}
+	return nil
+}
+
+// Component returns the bundled component with the given type and name, or nil if there is none
+func (m *Manifest) Component(componentType, name string) *Component {
+	for i := range m.Components {
+		if m.Components[i].Type == componentType && m.Components[i].Name == name {
+			return &m.Components[i]
+		}
+	}
+	return nil
+}
+
+// NewArtifact records a file that has been written into the bundle directory
+func NewArtifact(dir, file string) (Artifact, error) {
+	sum, err := checksum(filepath.Join(dir, filepath.FromSlash(file)))
+	if err != nil {
+		return Artifact{}, err
+	}
+	return Artifact{File: file, SHA256: sum}, nil
+}
+
+// Open opens the artifact in the bundle directory after verifying its checksum. The checksum is
+// computed on the returned file, so it cannot be replaced between the check and the read.
+func (a Artifact) Open(dir string) (*os.File, error) {
+	if a.File == "" || !filepath.IsLocal(filepath.FromSlash(a.File)) {
+		return nil, fmt.Errorf("invalid bundle file %q", a.File)
+	}
+
+	file, err := os.Open(filepath.Join(dir, filepath.FromSlash(a.File)))
+	if err != nil {
+		return nil, err
+	}
+
+	sum, err := hashReader(file)
+	if err == nil && !strings.EqualFold(sum, a.SHA256) {
+		err = fmt.Errorf("checksum mismatch for %s: expected sha256 %s, got %s", a.File, a.SHA256, sum)
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// Pack writes the contents of srcDir as a gzip-compressed tarball into the bundle directory.
+// The tarball holds a single top-level directory named like srcDir.
+func Pack(srcDir, dir, file string) (Artifact, error) {
+	target := filepath.Join(dir, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return Artifact{}, err
+	}
+
+	out, err := os.Create(target)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to create %s: %w", file, err)
+	}
+	defer out.Close()
+
+	if err := writeTarball(out, srcDir); err != nil {
+		return Artifact{}, fmt.Errorf("failed to pack %s: %w", srcDir, err)
+	}
+	if err := out.Close(); err != nil {
+		return Artifact{}, err
+	}
+	return NewArtifact(dir, file)
+}
+
+// writeTarball writes srcDir to w as a gzip-compressed tarball
+func writeTarball(w io.Writer, srcDir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	top := filepath.Base(srcDir)
+
+	err := filepath.WalkDir(srcDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(top, rel))
+		if entry.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// checksum returns the hex-encoded SHA-256 digest of a file
+func checksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return hashReader(file)
+}
+
+// hashReader returns the hex-encoded SHA-256 of the rest of r
+func hashReader(r io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", fmt.Errorf("failed to compute checksum: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,93 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPackAndOpen(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "Math")
+	if err := os.MkdirAll(filepath.Join(src, "includes"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "includes", "Math.php"), []byte("<?php\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	artifact, err := Pack(src, dir, "extensions/Math.tar.gz")
+	if err != nil {
+		t.Fatalf("Failed to pack: %v", err)
+	}
+	if artifact.File != "extensions/Math.tar.gz" || len(artifact.SHA256) != 64 {
+		t.Errorf("Unexpected artifact: %+v", artifact)
+	}
+
+	file, err := artifact.Open(dir)
+	if err != nil {
+		t.Fatalf("Failed to open artifact: %v", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+	}
+	if !slices.Equal(names, []string{"Math/", "Math/includes/", "Math/includes/Math.php"}) {
+		t.Errorf("Unexpected tarball entries: %v", names)
+	}
+
+	tampered := artifact
+	tampered.SHA256 = strings.Repeat("0", 64)
+	if _, err := tampered.Open(dir); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+
+	escaping := Artifact{File: "../Math.tar.gz", SHA256: artifact.SHA256}
+	if _, err := escaping.Open(dir); err == nil {
+		t.Error("Expected a file outside the bundle to be rejected")
+	}
+}
+
+func TestManifestReadWrite(t *testing.T) {
+	dir := t.TempDir()
+	m := &Manifest{
+		MediaWiki: Core{Version: "1.43.1", Artifact: Artifact{File: "mediawiki-1.43.1.tar.gz", SHA256: "abc"}},
+		Components: []Component{
+			{Type: "extension", Name: "Math", Distributor: "extdist", Dir: "extensions/Math"},
+			{Type: "skin", Name: "Vector", Distributor: "extdist", Dir: "skins/Vector"},
+		},
+	}
+	if err := m.Write(dir); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	read, err := Read(dir)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if read.MediaWiki.File != "mediawiki-1.43.1.tar.gz" || read.Component("skin", "Vector") == nil || read.Component("extension", "Vector") != nil {
+		t.Errorf("Unexpected manifest: %+v", read)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), []byte(`{"format": 2}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(dir); err == nil || !strings.Contains(err.Error(), "unsupported bundle format 2") {
+		t.Errorf("Expected an unsupported format error, got %v", err)
+	}
+}
@@ -2,6 +2,11 @@ package config
 
 import (
 	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -10,22 +15,69 @@ type Config struct {
 	MediaWiki  MediaWikiConfig
 	Extensions []ComponentConfig
 	Skins      []ComponentConfig
+	Sources    SourcesConfig
+	HTTP       HTTPConfig
+	Profiles   map[string]*Profile // named overlays, applied with WithProfile
 }
 
 // MediaWikiConfig holds MediaWiki core configuration
 type MediaWikiConfig struct {
 	Version string `ini:"version"`
+	// SettingsFile is the generated PHP file with load statements, relative to the installation.
+	// Load statements are only generated if it is set.
+	SettingsFile string `ini:"settings_file"`
+	// PHP is the PHP binary used to check requirements, "php" from the PATH if empty
+	PHP string `ini:"php"`
+	// Fallback are the ExtDist branches tried in order if a component has no snapshot for its branch
+	Fallback []string `ini:"fallback"`
 }
 
+// Component types
+const (
+	TypeExtension = "extension"
+	TypeSkin      = "skin"
+)
+
 // ComponentConfig represents an extension or skin configuration
 type ComponentConfig struct {
+	Type        string // TypeExtension or TypeSkin
 	Distributor string
 	Name        string
 	Version     string
+	Dir         string   // directory name to install into, derived from Name if empty
+	Post        []string // shell commands to run in the installed directory after the update
+	PHP         []string // PHP statements written after the load statement in the settings file
+	Exclude     []string // paths relative to the component directory that are not installed
+	SHA256      string   // expected SHA-256 checksum of the downloaded archive
+	Fallback    []string // ExtDist branches to try in order, overriding the global fallback; "none" disables it
+	Required    bool     // whether a failure to download this component aborts the update
+	Submodules  bool     // whether to fetch Git submodules
+	Auth        string   // credential source for Git: env:VAR, netrc[:path] or ssh:path
+	File        string   // configuration file the component was declared in
+	Line        int      // line in File the component was declared on
+
+	position int // declaration order across the configuration and included files
 }
 
-// LoadConfig loads configuration from an INI file
+// componentSectionPattern matches per-component sections like [extension "Math"]
+var componentSectionPattern = regexp.MustCompile(`^(extension|skin)\s+"([^"]+)"$`)
+
+// sha256Pattern matches a hexadecimal SHA-256 checksum
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// fallbackBranchPattern matches the ExtDist branches a fallback chain may contain
+var fallbackBranchPattern = regexp.MustCompile(`^(REL\d+_\d+|master)$`)
+
+// NoFallback disables the global fallback chain for a component
+const NoFallback = "none"
+
+// LoadConfig loads configuration from a file. The format is chosen by the file extension:
+// .yaml/.yml, .toml and .json are structured formats, anything else is INI.
 func LoadConfig(configPath string) (*Config, error) {
+	if format := Format(configPath); format != "ini" {
+		return loadStructuredConfig(configPath, format)
+	}
+
 	ini, err := LoadINIFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config file: %w", err)
@@ -35,38 +87,393 @@ func LoadConfig(configPath string) (*Config, error) {
 
 	// Load MediaWiki section
 	config.MediaWiki.Version = ini.GetFirstValue("mediawiki", "version")
+	config.MediaWiki.SettingsFile = ini.GetFirstValue("mediawiki", "settings_file")
+	config.MediaWiki.PHP = ini.GetFirstValue("mediawiki", "php")
+	if value := ini.GetFirstValue("mediawiki", "fallback"); value != "" {
+		config.MediaWiki.Fallback, err = ParseFallback(value, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fallback in [mediawiki]: %w", err)
+		}
+	}
 
 	// Load Extensions section
-	config.Extensions = parseComponentsFromINI(ini, "extensions")
+	config.Extensions, err = parseComponentsFromINI(ini, "extensions", TypeExtension)
+	if err != nil {
+		return nil, err
+	}
 
 	// Load Skins section
-	config.Skins = parseComponentsFromINI(ini, "skins")
+	config.Skins, err = parseComponentsFromINI(ini, "skins", TypeSkin)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load per-component sections
+	for _, sectionName := range ini.SectionNames() {
+		matches := componentSectionPattern.FindStringSubmatch(sectionName)
+		if matches == nil {
+			continue
+		}
+
+		component, err := parseComponentSection(ini, sectionName, matches[1], matches[2])
+		if err != nil {
+			return nil, err
+		}
+
+		if component.Type == TypeExtension {
+			config.Extensions = append(config.Extensions, component)
+		} else {
+			config.Skins = append(config.Skins, component)
+		}
+	}
+
+	// Keep components in the order they are declared in the file
+	sortByPosition(config.Extensions)
+	sortByPosition(config.Skins)
+
+	// Load mirrors
+	config.Sources, err = parseSourcesFromINI(ini)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load HTTP client settings
+	config.HTTP, err = parseHTTPFromINI(ini)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load profile sections
+	config.Profiles, err = parseProfilesFromINI(ini)
+	if err != nil {
+		return nil, err
+	}
 
 	return config, nil
 }
 
+// DirName returns the name of the directory the component is installed into
+func (c ComponentConfig) DirName() string {
+	if c.Dir != "" {
+		return c.Dir
+	}
+	if c.Distributor == "git" {
+		return RepoName(c.Name)
+	}
+	return c.Name
+}
+
+// RepoName extracts the repository name from a Git URL
+func RepoName(repoURL string) string {
+	// Remove .git suffix and extract last part of URL
+	repoURL = strings.TrimSuffix(repoURL, ".git")
+	parts := strings.Split(repoURL, "/")
+	if len(parts) > 0 {
+		return parts[len(parts)-1]
+	}
+	return "unknown"
+}
+
+// IsComponentSection reports whether sectionName is a per-component section like [extension "Math"]
+func IsComponentSection(sectionName string) bool {
+	return componentSectionPattern.MatchString(sectionName)
+}
+
+// sortByPosition sorts components by the position they are declared at, including included files
+func sortByPosition(components []ComponentConfig) {
+	sort.SliceStable(components, func(i, j int) bool {
+		return components[i].position < components[j].position
+	})
+}
+
 // parseComponentsFromINI parses component configurations from a SimpleINI section
-func parseComponentsFromINI(ini *SimpleINI, sectionName string) []ComponentConfig {
+func parseComponentsFromINI(ini *SimpleINI, sectionName, componentType string) ([]ComponentConfig, error) {
 	var components []ComponentConfig
 
-	section := ini.GetSection(sectionName)
-	for distributor, values := range section {
-		for _, value := range values {
-			// Parse format: <extension>|<optional version>
-			parts := strings.SplitN(value, "|", 2)
-			name := parts[0]
-			version := ""
-			if len(parts) > 1 {
-				version = parts[1]
-			}
+	for _, entry := range ini.GetEntries(sectionName) {
+		component, err := parseComponentEntry(entry, componentType)
+		if err != nil {
+			return nil, err
+		}
+		components = append(components, component)
+	}
+
+	return components, nil
+}
+
+// flagAttributes are the attributes that may be given without a value
+var flagAttributes = []string{"required", "submodules"}
+
+// parseComponentEntry parses a single "<distributor>=<name>|<version>|<attributes>..." entry.
+// The version may be left out if attributes follow: a field after the name that contains "=" or
+// is a flag is an attribute. Values cannot contain "|"; per-component sections allow it.
+func parseComponentEntry(entry Entry, componentType string) (ComponentConfig, error) {
+	value, file, line := entry.Value, entry.File, entry.Line
+
+	parts := strings.Split(value, "|")
+	component := ComponentConfig{
+		Type:        componentType,
+		Distributor: entry.Key,
+		Name:        strings.TrimSpace(parts[0]),
+		File:        file,
+		Line:        line,
+		position:    entry.position,
+	}
+
+	if component.Name == "" {
+		return component, fmt.Errorf("%s:%d: missing component name in %q", file, line, value)
+	}
+
+	attributes := parts[1:]
+	if len(attributes) > 0 && !isAttribute(attributes[0]) {
+		component.Version = strings.TrimSpace(attributes[0])
+		attributes = attributes[1:]
+	}
+
+	for _, attribute := range attributes {
+		key, attrValue, hasValue := strings.Cut(attribute, "=")
+		if err := component.setAttribute(strings.TrimSpace(key), strings.TrimSpace(attrValue), hasValue); err != nil {
+			return component, fmt.Errorf("%s:%d: invalid component %q: %w", file, line, value, err)
+		}
+	}
+
+	if err := component.Validate(); err != nil {
+		return component, fmt.Errorf("%s:%d: invalid component %q: %w", file, line, value, err)
+	}
+
+	return component, nil
+}
+
+// isAttribute reports whether a field of a component entry is an attribute rather than a version
+func isAttribute(field string) bool {
+	field = strings.TrimSpace(field)
+	return strings.Contains(field, "=") || slices.Contains(flagAttributes, field)
+}
+
+// parseComponentSection parses a per-component section like [extension "Math"]
+func parseComponentSection(ini *SimpleINI, sectionName, componentType, name string) (ComponentConfig, error) {
+	component := ComponentConfig{
+		Type:        componentType,
+		Distributor: "extdist",
+		Name:        name,
+		File:        ini.SectionFile(sectionName),
+		Line:        ini.SectionLine(sectionName),
+		position:    ini.sectionPosition(sectionName),
+	}
+
+	for _, entry := range ini.GetEntries(sectionName) {
+		var err error
+		switch entry.Key {
+		case "distributor":
+			component.Distributor = entry.Value
+		case "version":
+			component.Version = entry.Value
+		case "repository":
+			component.Name = entry.Value
+		default:
+			err = component.setAttribute(entry.Key, entry.Value, true)
+		}
+
+		if err != nil {
+			return component, fmt.Errorf("%s:%d: invalid [%s]: %w", entry.File, entry.Line, sectionName, err)
+		}
+	}
+
+	// The section name is the directory name of a Git component
+	if component.Distributor == "git" {
+		if component.Name == name {
+			return component, fmt.Errorf("%s:%d: invalid [%s]: git components require a repository", component.File, component.Line, sectionName)
+		}
+		if component.Dir == "" {
+			component.Dir = name
+		}
+	}
+
+	if err := component.Validate(); err != nil {
+		return component, fmt.Errorf("%s:%d: invalid [%s]: %w", component.File, component.Line, sectionName, err)
+	}
+
+	return component, nil
+}
 
-			components = append(components, ComponentConfig{
-				Distributor: distributor,
-				Name:        name,
-				Version:     version,
-			})
+// setAttribute applies a single "key=value" or flag attribute to the component
+func (c *ComponentConfig) setAttribute(key, value string, hasValue bool) error {
+	switch key {
+	case "dir":
+		if !isValidDirName(value) {
+			return fmt.Errorf("invalid directory name %q", value)
 		}
+		c.Dir = value
+	case "post":
+		if value == "" {
+			return fmt.Errorf("empty post-install command")
+		}
+		c.Post = append(c.Post, value)
+	case "php":
+		if value == "" {
+			return fmt.Errorf("empty PHP setting")
+		}
+		c.PHP = append(c.PHP, value)
+	case "exclude":
+		cleaned, ok := cleanExclude(value)
+		if !ok {
+			return fmt.Errorf("invalid exclude path %q", value)
+		}
+		c.Exclude = append(c.Exclude, cleaned)
+	case "fallback":
+		fallback, err := ParseFallback(value, true)
+		if err != nil {
+			return err
+		}
+		c.Fallback = fallback
+	case "sha256":
+		if !sha256Pattern.MatchString(value) {
+			return fmt.Errorf("invalid sha256 checksum %q", value)
+		}
+		c.SHA256 = strings.ToLower(value)
+	case "required":
+		flag, err := parseFlag(value, hasValue)
+		if err != nil {
+			return fmt.Errorf("invalid required flag: %w", err)
+		}
+		c.Required = flag
+	case "submodules":
+		flag, err := parseFlag(value, hasValue)
+		if err != nil {
+			return fmt.Errorf("invalid submodules flag: %w", err)
+		}
+		c.Submodules = flag
+	case "auth":
+		if !isValidAuth(value) {
+			return fmt.Errorf("invalid auth %q (expected env:VAR, netrc, netrc:path or ssh:path)", value)
+		}
+		c.Auth = value
+	default:
+		return fmt.Errorf("unknown attribute %q", key)
 	}
 
-	return components
+	return nil
+}
+
+// Validate reports invalid values of the component. Components loaded from a configuration file
+// have passed it; components built in code must pass it before they are downloaded, because the
+// directory and exclude paths decide which files are replaced and removed.
+func (c ComponentConfig) Validate() error {
+	if c.Type != TypeExtension && c.Type != TypeSkin {
+		return fmt.Errorf("invalid type %q (expected %s or %s)", c.Type, TypeExtension, TypeSkin)
+	}
+	if c.Distributor == "" {
+		return fmt.Errorf("missing distributor")
+	}
+	if c.Name == "" {
+		return fmt.Errorf("missing component name")
+	}
+	if dir := c.DirName(); !isValidDirName(dir) {
+		return fmt.Errorf("invalid directory name %q", dir)
+	}
+	for _, exclude := range c.Exclude {
+		if _, ok := cleanExclude(exclude); !ok {
+			return fmt.Errorf("invalid exclude path %q", exclude)
+		}
+	}
+	if slices.Contains(c.Post, "") {
+		return fmt.Errorf("empty post-install command")
+	}
+	if slices.Contains(c.PHP, "") {
+		return fmt.Errorf("empty PHP setting")
+	}
+	if c.SHA256 != "" && !sha256Pattern.MatchString(c.SHA256) {
+		return fmt.Errorf("invalid sha256 checksum %q", c.SHA256)
+	}
+	if c.Auth != "" && !isValidAuth(c.Auth) {
+		return fmt.Errorf("invalid auth %q (expected env:VAR, netrc, netrc:path or ssh:path)", c.Auth)
+	}
+	if len(c.Fallback) > 0 && !slices.Equal(c.Fallback, []string{NoFallback}) {
+		if _, err := ParseFallback(strings.Join(c.Fallback, ","), false); err != nil {
+			return err
+		}
+	}
+
+	// Attributes that are not supported by the component's distributor
+	switch c.Distributor {
+	case "git":
+		if c.SHA256 != "" {
+			return fmt.Errorf("sha256 is not supported for git components, pin a commit SHA as the version instead")
+		}
+		if len(c.Fallback) > 0 {
+			return fmt.Errorf("fallback is only supported for extdist components")
+		}
+	case "extdist":
+		if c.Submodules || c.Auth != "" {
+			return fmt.Errorf("submodules and auth are only supported for git components")
+		}
+	}
+	return nil
+}
+
+// ParseFallback parses a comma-separated fallback chain like "REL1_42,master". If allowNone
+// is set, the chain may be "none" to disable the global fallback.
+func ParseFallback(value string, allowNone bool) ([]string, error) {
+	if allowNone && strings.TrimSpace(value) == NoFallback {
+		return []string{NoFallback}, nil
+	}
+
+	var branches []string
+	for _, branch := range strings.Split(value, ",") {
+		branch = strings.TrimSpace(branch)
+		if !fallbackBranchPattern.MatchString(branch) {
+			return nil, fmt.Errorf("invalid fallback branch %q (expected REL<major>_<minor> or master)", branch)
+		}
+		branches = append(branches, branch)
+	}
+	return branches, nil
+}
+
+// FallbackBranches returns the ExtDist branches to try, in order, if the component has no
+// snapshot for its branch: its own fallback chain, or the global one
+func (c *Config) FallbackBranches(component ComponentConfig) []string {
+	switch {
+	case component.Distributor != "extdist":
+		return nil
+	case len(component.Fallback) == 1 && component.Fallback[0] == NoFallback:
+		return nil
+	case len(component.Fallback) > 0:
+		return component.Fallback
+	}
+	return c.MediaWiki.Fallback
+}
+
+// parseFlag parses a boolean attribute; a bare flag without a value is true
+func parseFlag(value string, hasValue bool) (bool, error) {
+	if !hasValue || value == "" {
+		return true, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// isValidDirName reports whether name is a single directory name that stays inside the
+// extensions or skins directory
+func isValidDirName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// cleanExclude cleans an exclude path and reports whether it stays inside the component directory
+func cleanExclude(value string) (string, bool) {
+	cleaned := path.Clean(strings.ReplaceAll(value, `\`, "/"))
+	if value == "" || path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// isValidAuth reports whether value is a supported Git credential source
+func isValidAuth(value string) bool {
+	kind, arg, hasArg := strings.Cut(value, ":")
+	switch kind {
+	case "env", "ssh":
+		return hasArg && arg != ""
+	case "netrc":
+		return !hasArg || arg != ""
+	}
+	return false
 }
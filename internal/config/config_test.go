@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -76,3 +77,402 @@ func TestLoadConfigFileNotExists(t *testing.T) {
 		t.Error("Expected error for nonexistent file, got nil")
 	}
 }
+
+func TestLoadConfigComponentAttributes(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "test.ini")
+
+	configContent := `[mediawiki]
+version=1.43.1
+
+[extensions]
+git=git@github.com:example/private.git|main|submodules|auth=ssh:/home/deploy/.ssh/id_ed25519|dir=Private
+
+[skins]
+git=https://github.com/StarCitizenTools/mediawiki-skins-Citizen.git|main|dir=Citizen|post=npm ci|exclude=tests/|exclude=docs
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	ext := config.Extensions[0]
+	if ext.Version != "main" || !ext.Submodules || ext.Auth != "ssh:/home/deploy/.ssh/id_ed25519" || ext.Dir != "Private" {
+		t.Errorf("Extension attributes not parsed correctly: %+v", ext)
+	}
+
+	skin := config.Skins[0]
+	if skin.Dir != "Citizen" || len(skin.Post) != 1 || skin.Post[0] != "npm ci" || len(skin.Exclude) != 2 || skin.Exclude[0] != "tests" || skin.Exclude[1] != "docs" {
+		t.Errorf("Skin attributes not parsed correctly: %+v", skin)
+	}
+}
+
+func TestLoadConfigAttributesWithoutVersion(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "test.ini")
+
+	configContent := `[mediawiki]
+version=1.43.1
+
+[extensions]
+extdist=Math|required
+
+[skins]
+git=https://github.com/StarCitizenTools/mediawiki-skins-Citizen.git|dir=Citizen
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	ext := config.Extensions[0]
+	if ext.Version != "" || !ext.Required {
+		t.Errorf("Expected required extension without version, got %+v", ext)
+	}
+
+	skin := config.Skins[0]
+	if skin.Version != "" || skin.Dir != "Citizen" {
+		t.Errorf("Expected skin in dir Citizen without version, got %+v", skin)
+	}
+}
+
+func TestLoadConfigInvalidAttribute(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "test.ini")
+
+	configContent := `[extensions]
+git=https://github.com/example/repo.git|main|auth=password:hunter2
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	if _, err := LoadConfig(configPath); err == nil {
+		t.Error("Expected error for invalid auth attribute, got nil")
+	}
+}
+
+func TestLoadConfigFallback(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "test.ini")
+
+	configContent := `[mediawiki]
+version=1.43.1
+fallback=REL1_42, master
+
+[extensions]
+extdist=Math
+extdist=Echo||fallback=master
+extdist=Cite||fallback=none
+git=https://github.com/example/repo.git|main
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	expected := []string{"REL1_42,master", "master", "", ""}
+	for i, component := range config.Extensions {
+		if actual := strings.Join(config.FallbackBranches(component), ","); actual != expected[i] {
+			t.Errorf("Expected fallback %q for %s, got %q", expected[i], component.Name, actual)
+		}
+	}
+
+	for _, invalid := range []string{
+		"[mediawiki]\nfallback=REL1_42,none\n",
+		"[extensions]\nextdist=Math||fallback=1.42\n",
+		"[extensions]\ngit=https://github.com/example/repo.git|main|fallback=master\n",
+	} {
+		if err := os.WriteFile(configPath, []byte(invalid), 0o644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+		if _, err := LoadConfig(configPath); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestLoadConfigSources(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "test.ini")
+
+	configContent := `[mediawiki]
+version=1.43.1
+
+[sources]
+releases=https://mirror.example/mediawiki/
+releases=/srv/mirror/mediawiki
+extdist=file:///srv/mirror/extdist
+extdist_api=none
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	sources := config.Sources.Override(SourcesConfig{Releases: []string{"https://other.example/"}})
+	if strings.Join(config.Sources.Releases, ",") != "https://mirror.example/mediawiki/,/srv/mirror/mediawiki" {
+		t.Errorf("Unexpected releases: %v", config.Sources.Releases)
+	}
+	if strings.Join(sources.Releases, ",") != "https://other.example/" || strings.Join(sources.ExtDistAPI, ",") != NoSource || len(sources.Composer) != 0 {
+		t.Errorf("Unexpected overridden sources: %+v", sources)
+	}
+
+	for _, invalid := range []string{
+		"[sources]\nreleases=mirror.example\n",
+		"[sources]\nextdist=none\n",
+		"[sources]\nextdist_api=none\nextdist_api=https://www.mediawiki.org/w/api.php\n",
+	} {
+		if err := os.WriteFile(configPath, []byte(invalid), 0o644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+		if _, err := LoadConfig(configPath); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestLoadConfigHTTP(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "test.ini")
+
+	configContent := `[mediawiki]
+version=1.43.1
+
+[http]
+proxy=http://proxy.example:3128
+no_proxy=localhost, .corp.example
+ca_file=/etc/ssl/corp.pem
+ca_file=/etc/ssl/mirror.pem
+client_cert=/etc/ssl/client.pem
+client_key=/etc/ssl/client.key
+user_agent_suffix=corp-wiki/1.0
+rate_limit=0.5
+max_concurrent=2
+max_retries=-1
+
+[sources]
+extdist_rate_limit=1
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if config.HTTP.Proxy != "http://proxy.example:3128" || strings.Join(config.HTTP.NoProxy, ",") != "localhost,.corp.example" ||
+		strings.Join(config.HTTP.CAFiles, ",") != "/etc/ssl/corp.pem,/etc/ssl/mirror.pem" || config.HTTP.UserAgentSuffix != "corp-wiki/1.0" {
+		t.Errorf("Unexpected HTTP settings: %+v", config.HTTP)
+	}
+	if config.HTTP.RateLimit != 0.5 || config.HTTP.Burst != 0 || config.HTTP.MaxConcurrent != 2 || config.HTTP.MaxRetries != -1 {
+		t.Errorf("Unexpected HTTP limits: %+v", config.HTTP)
+	}
+	if len(config.Sources.RateLimits) != 1 || config.Sources.RateLimits["extdist"] != 1 {
+		t.Errorf("Unexpected source rate limits: %v", config.Sources.RateLimits)
+	}
+
+	settings := config.HTTP.Override(HTTPConfig{Proxy: "socks5://127.0.0.1:1080"})
+	if settings.Proxy != "socks5://127.0.0.1:1080" || settings.ClientKey != "/etc/ssl/client.key" {
+		t.Errorf("Unexpected overridden HTTP settings: %+v", settings)
+	}
+
+	for _, invalid := range []string{
+		"[http]\nproxy=proxy.example:3128\n",
+		"[http]\nproxy=ftp://proxy.example\n",
+		"[http]\nclient_cert=/etc/ssl/client.pem\n",
+		"[http]\nrate_limit=fast\n",
+		"[http]\nburst=-1\n",
+		"[http]\nmax_concurrent=2.5\n",
+		"[sources]\nreleases_rate_limit=slow\n",
+	} {
+		if err := os.WriteFile(configPath, []byte(invalid), 0o644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+		if _, err := LoadConfig(configPath); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestLoadConfigComponentSections(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "test.ini")
+
+	configContent := `[mediawiki]
+version=1.43.1
+settings_file=LocalSettings.extensions.php
+
+[extension "Math"]
+version=REL1_43
+sha256=E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855
+required=true
+php=$wgMathValidModes = [ 'source', 'native' ];
+
+[skin "Citizen"]
+distributor=git
+repository=https://github.com/StarCitizenTools/mediawiki-skins-Citizen.git
+version=main
+post=npm ci
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if len(config.Extensions) != 1 || len(config.Skins) != 1 {
+		t.Fatalf("Expected 1 extension and 1 skin, got %d and %d", len(config.Extensions), len(config.Skins))
+	}
+
+	if config.MediaWiki.SettingsFile != "LocalSettings.extensions.php" {
+		t.Errorf("Expected settings file LocalSettings.extensions.php, got %q", config.MediaWiki.SettingsFile)
+	}
+
+	ext := config.Extensions[0]
+	if ext.Distributor != "extdist" || ext.Name != "Math" || ext.Version != "REL1_43" || !ext.Required || ext.Line != 5 ||
+		len(ext.PHP) != 1 || ext.PHP[0] != "$wgMathValidModes = [ 'source', 'native' ];" ||
+		ext.SHA256 != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("Extension section not parsed correctly: %+v", ext)
+	}
+
+	skin := config.Skins[0]
+	if skin.Distributor != "git" || skin.Name != "https://github.com/StarCitizenTools/mediawiki-skins-Citizen.git" || skin.Dir != "Citizen" || skin.Version != "main" {
+		t.Errorf("Skin section not parsed correctly: %+v", skin)
+	}
+}
+
+func TestLoadConfigErrorLineNumber(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "test.ini")
+
+	configContent := `[mediawiki]
+version=1.43.1
+
+[extensions]
+extdist=Math|REL1_43|required
+extdist=Cite||unknown=value
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	_, err := LoadConfig(configPath)
+	if err == nil {
+		t.Fatal("Expected error for unknown attribute, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "test.ini:6:") {
+		t.Errorf("Expected error to reference line 6, got: %v", err)
+	}
+}
+
+func TestLoadConfigPreservesComponentOrder(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "test.ini")
+
+	configContent := `[mediawiki]
+version=1.43.1
+
+[extensions]
+git=https://github.com/example/First.git
+extdist=Second
+git=https://github.com/example/Third.git
+
+[extension "Fourth"]
+version=REL1_43
+
+[extensions]
+extdist=Fifth
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	var names []string
+	for _, ext := range config.Extensions {
+		names = append(names, ext.Name)
+	}
+
+	expected := []string{"https://github.com/example/First.git", "Second", "https://github.com/example/Third.git", "Fourth", "Fifth"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected extensions in config order %v, got %v", expected, names)
+	}
+}
+
+func TestComponentDirName(t *testing.T) {
+	tests := []struct {
+		component ComponentConfig
+		expected  string
+	}{
+		{ComponentConfig{Distributor: "extdist", Name: "Math"}, "Math"},
+		{ComponentConfig{Distributor: "git", Name: "https://github.com/StarCitizenTools/mediawiki-skins-Citizen.git"}, "mediawiki-skins-Citizen"},
+		{ComponentConfig{Distributor: "git", Name: "https://gerrit.wikimedia.org/r/mediawiki/extensions/Math"}, "Math"},
+		{ComponentConfig{Distributor: "git", Name: "https://github.com/example/skin.git", Dir: "Custom"}, "Custom"},
+	}
+
+	for _, test := range tests {
+		if result := test.component.DirName(); result != test.expected {
+			t.Errorf("Expected %s for %+v, got %s", test.expected, test.component, result)
+		}
+	}
+}
+
+func TestComponentValidate(t *testing.T) {
+	valid := ComponentConfig{Type: TypeExtension, Distributor: "extdist", Name: "Math", Exclude: []string{"tests"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected %+v to be valid, got %v", valid, err)
+	}
+
+	for _, component := range []ComponentConfig{
+		{Type: "plugin", Distributor: "extdist", Name: "Math"},
+		{Type: TypeExtension, Name: "Math"},
+		{Type: TypeExtension, Distributor: "extdist", Name: "Math", Dir: "../.."},
+		{Type: TypeExtension, Distributor: "extdist", Name: "../Math"},
+		{Type: TypeExtension, Distributor: "git", Name: "https://example.org/.."},
+		{Type: TypeExtension, Distributor: "extdist", Name: "Math", Exclude: []string{"../../LocalSettings.php"}},
+		{Type: TypeExtension, Distributor: "extdist", Name: "Math", Exclude: []string{"/etc"}},
+		{Type: TypeExtension, Distributor: "extdist", Name: "Math", SHA256: "abc"},
+		{Type: TypeExtension, Distributor: "git", Name: "https://example.org/Math.git", Auth: "token"},
+		{Type: TypeExtension, Distributor: "extdist", Name: "Math", Fallback: []string{"none", "master"}},
+	} {
+		if err := component.Validate(); err == nil {
+			t.Errorf("Expected %+v to be rejected", component)
+		}
+	}
+}
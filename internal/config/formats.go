@@ -0,0 +1,330 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig is the structure of YAML, TOML and JSON configuration files.
+// It is described by config.schema.json in the repository root.
+type fileConfig struct {
+	MediaWiki  fileMediaWiki          `json:"mediawiki" yaml:"mediawiki" toml:"mediawiki"`
+	Extensions []fileComponent        `json:"extensions" yaml:"extensions" toml:"extensions"`
+	Skins      []fileComponent        `json:"skins" yaml:"skins" toml:"skins"`
+	Sources    fileSources            `json:"sources" yaml:"sources" toml:"sources"`
+	HTTP       fileHTTP               `json:"http" yaml:"http" toml:"http"`
+	Profiles   map[string]fileProfile `json:"profiles" yaml:"profiles" toml:"profiles"`
+}
+
+// fileSources is the sources section of a structured configuration file
+type fileSources struct {
+	Releases   []string `json:"releases" yaml:"releases" toml:"releases"`
+	ExtDist    []string `json:"extdist" yaml:"extdist" toml:"extdist"`
+	ExtDistAPI []string `json:"extdist_api" yaml:"extdist_api" toml:"extdist_api"`
+	Composer   []string `json:"composer" yaml:"composer" toml:"composer"`
+	// RateLimits are keyed by source, like "extdist"
+	RateLimits map[string]float64 `json:"rate_limits" yaml:"rate_limits" toml:"rate_limits"`
+}
+
+// fileHTTP is the http section of a structured configuration file
+type fileHTTP struct {
+	Proxy           string   `json:"proxy" yaml:"proxy" toml:"proxy"`
+	NoProxy         []string `json:"no_proxy" yaml:"no_proxy" toml:"no_proxy"`
+	CAFiles         []string `json:"ca_file" yaml:"ca_file" toml:"ca_file"`
+	ClientCert      string   `json:"client_cert" yaml:"client_cert" toml:"client_cert"`
+	ClientKey       string   `json:"client_key" yaml:"client_key" toml:"client_key"`
+	UserAgentSuffix string   `json:"user_agent_suffix" yaml:"user_agent_suffix" toml:"user_agent_suffix"`
+	RateLimit       float64  `json:"rate_limit" yaml:"rate_limit" toml:"rate_limit"`
+	Burst           int      `json:"burst" yaml:"burst" toml:"burst"`
+	MaxConcurrent   int      `json:"max_concurrent" yaml:"max_concurrent" toml:"max_concurrent"`
+	MaxRetries      int      `json:"max_retries" yaml:"max_retries" toml:"max_retries"`
+}
+
+// fileProfile is a profile overlay in a structured configuration file
+type fileProfile struct {
+	MediaWiki        fileMediaWiki   `json:"mediawiki" yaml:"mediawiki" toml:"mediawiki"`
+	Extensions       []fileComponent `json:"extensions" yaml:"extensions" toml:"extensions"`
+	Skins            []fileComponent `json:"skins" yaml:"skins" toml:"skins"`
+	RemoveExtensions []string        `json:"remove_extensions" yaml:"remove_extensions" toml:"remove_extensions"`
+	RemoveSkins      []string        `json:"remove_skins" yaml:"remove_skins" toml:"remove_skins"`
+}
+
+// fileMediaWiki is the mediawiki section of a structured configuration file
+type fileMediaWiki struct {
+	Version      string   `json:"version" yaml:"version" toml:"version"`
+	SettingsFile string   `json:"settings_file" yaml:"settings_file" toml:"settings_file"`
+	PHP          string   `json:"php" yaml:"php" toml:"php"`
+	Fallback     []string `json:"fallback" yaml:"fallback" toml:"fallback"`
+}
+
+// fileComponent is an extension or skin in a structured configuration file
+type fileComponent struct {
+	Name        string   `json:"name" yaml:"name" toml:"name"`
+	Distributor string   `json:"distributor" yaml:"distributor" toml:"distributor"`
+	Repository  string   `json:"repository" yaml:"repository" toml:"repository"`
+	Version     string   `json:"version" yaml:"version" toml:"version"`
+	Dir         string   `json:"dir" yaml:"dir" toml:"dir"`
+	Exclude     []string `json:"exclude" yaml:"exclude" toml:"exclude"`
+	Post        []string `json:"post" yaml:"post" toml:"post"`
+	PHP         []string `json:"php" yaml:"php" toml:"php"`
+	SHA256      string   `json:"sha256" yaml:"sha256" toml:"sha256"`
+	Fallback    []string `json:"fallback" yaml:"fallback" toml:"fallback"`
+	Required    bool     `json:"required" yaml:"required" toml:"required"`
+	Submodules  bool     `json:"submodules" yaml:"submodules" toml:"submodules"`
+	Auth        string   `json:"auth" yaml:"auth" toml:"auth"`
+}
+
+// Format returns the configuration format for a file based on its extension:
+// "yaml", "toml", "json" or "ini"
+func Format(configPath string) string {
+	switch strings.ToLower(filepath.Ext(configPath)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	case ".json":
+		return "json"
+	default:
+		return "ini"
+	}
+}
+
+// loadStructuredConfig loads a YAML, TOML or JSON configuration file
+func loadStructuredConfig(configPath, format string) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config file: %w", err)
+	}
+
+	var file fileConfig
+	var lines map[string][]int
+	switch format {
+	case "yaml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil {
+			return nil, fmt.Errorf("%s: invalid YAML: %w", configPath, err)
+		}
+		lines = yamlComponentLines(data)
+	case "toml":
+		meta, err := toml.Decode(string(data), &file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid TOML: %w", configPath, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("%s: unknown key %q", configPath, undecoded[0].String())
+		}
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, fmt.Errorf("%s: invalid JSON: %w", configPath, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format: %s", format)
+	}
+
+	config := &Config{}
+	config.MediaWiki, err = file.MediaWiki.toMediaWikiConfig()
+	if err != nil {
+		return nil, fmt.Errorf("%s: mediawiki: %w", configPath, err)
+	}
+
+	config.Sources = SourcesConfig(file.Sources)
+	if err := config.Sources.Check(); err != nil {
+		return nil, fmt.Errorf("%s: sources: %w", configPath, err)
+	}
+
+	config.HTTP = HTTPConfig(file.HTTP)
+	if err := config.HTTP.Check(); err != nil {
+		return nil, fmt.Errorf("%s: http: %w", configPath, err)
+	}
+
+	config.Extensions, err = convertFileComponents(configPath, file.Extensions, TypeExtension, lines["extensions"])
+	if err != nil {
+		return nil, fmt.Errorf("%s: extensions%w", configPath, err)
+	}
+
+	config.Skins, err = convertFileComponents(configPath, file.Skins, TypeSkin, lines["skins"])
+	if err != nil {
+		return nil, fmt.Errorf("%s: skins%w", configPath, err)
+	}
+
+	config.Profiles = make(map[string]*Profile, len(file.Profiles))
+	for name, fileProfile := range file.Profiles {
+		if !profileSectionPattern.MatchString("profile:" + name) {
+			return nil, fmt.Errorf("%s: invalid profile name %q", configPath, name)
+		}
+
+		profile := &Profile{
+			RemoveExtensions: fileProfile.RemoveExtensions,
+			RemoveSkins:      fileProfile.RemoveSkins,
+		}
+
+		profile.MediaWiki, err = fileProfile.MediaWiki.toMediaWikiConfig()
+		if err != nil {
+			return nil, fmt.Errorf("%s: profiles.%s.mediawiki: %w", configPath, name, err)
+		}
+
+		profile.Extensions, err = convertFileComponents(configPath, fileProfile.Extensions, TypeExtension, lines["profiles."+name+".extensions"])
+		if err != nil {
+			return nil, fmt.Errorf("%s: profiles.%s.extensions%w", configPath, name, err)
+		}
+
+		profile.Skins, err = convertFileComponents(configPath, fileProfile.Skins, TypeSkin, lines["profiles."+name+".skins"])
+		if err != nil {
+			return nil, fmt.Errorf("%s: profiles.%s.skins%w", configPath, name, err)
+		}
+
+		config.Profiles[name] = profile
+	}
+
+	return config, nil
+}
+
+// toMediaWikiConfig converts a structured mediawiki section to a MediaWikiConfig
+func (f fileMediaWiki) toMediaWikiConfig() (MediaWikiConfig, error) {
+	mediaWiki := MediaWikiConfig{
+		Version:      f.Version,
+		SettingsFile: f.SettingsFile,
+		PHP:          f.PHP,
+	}
+
+	if len(f.Fallback) > 0 {
+		fallback, err := ParseFallback(strings.Join(f.Fallback, ","), false)
+		if err != nil {
+			return mediaWiki, fmt.Errorf("invalid fallback: %w", err)
+		}
+		mediaWiki.Fallback = fallback
+	}
+
+	return mediaWiki, nil
+}
+
+// yamlComponentLines returns the lines the components of a YAML configuration file are declared
+// on, keyed by the path of their list, like "extensions" or "profiles.staging.skins"
+func yamlComponentLines(data []byte) map[string][]int {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil || len(root.Content) == 0 {
+		return nil
+	}
+
+	lines := make(map[string][]int)
+	var walk func(prefix string, node *yaml.Node)
+	walk = func(prefix string, node *yaml.Node) {
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			switch {
+			case (key == "extensions" || key == "skins") && value.Kind == yaml.SequenceNode:
+				for _, item := range value.Content {
+					lines[prefix+key] = append(lines[prefix+key], item.Line)
+				}
+			case key == "profiles" && prefix == "" && value.Kind == yaml.MappingNode:
+				for j := 0; j+1 < len(value.Content); j += 2 {
+					walk("profiles."+value.Content[j].Value+".", value.Content[j+1])
+				}
+			}
+		}
+	}
+	walk("", root.Content[0])
+
+	return lines
+}
+
+// convertFileComponents converts structured components to ComponentConfig, applying
+// the same validation as the INI attributes. lines are the lines the components are declared
+// on, if known.
+func convertFileComponents(configPath string, files []fileComponent, componentType string, lines []int) ([]ComponentConfig, error) {
+	components := make([]ComponentConfig, 0, len(files))
+
+	for i, file := range files {
+		component, err := file.toComponentConfig(componentType)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		component.File = configPath
+		if i < len(lines) {
+			component.Line = lines[i]
+		}
+		components = append(components, component)
+	}
+
+	return components, nil
+}
+
+// toComponentConfig converts a structured component to a ComponentConfig
+func (f fileComponent) toComponentConfig(componentType string) (ComponentConfig, error) {
+	component := ComponentConfig{
+		Type:        componentType,
+		Distributor: f.Distributor,
+		Name:        f.Name,
+		Version:     f.Version,
+		Required:    f.Required,
+		Submodules:  f.Submodules,
+	}
+	if component.Distributor == "" {
+		component.Distributor = "extdist"
+	}
+
+	// Git components are identified by their repository and installed into a directory named after them
+	if component.Distributor == "git" {
+		if f.Repository == "" {
+			return component, fmt.Errorf("git component %q requires a repository", f.Name)
+		}
+		component.Name = f.Repository
+		if f.Dir == "" {
+			f.Dir = f.Name
+		}
+		if f.Name == "" {
+			f.Name = f.Repository
+		}
+	} else if f.Repository != "" {
+		return component, fmt.Errorf("repository is only supported for git components")
+	}
+
+	if component.Name == "" {
+		return component, fmt.Errorf("missing component name")
+	}
+
+	// Validate values the same way as INI attributes
+	var attributes [][2]string
+	for _, attribute := range [][2]string{{"dir", f.Dir}, {"sha256", f.SHA256}, {"auth", f.Auth}} {
+		if attribute[1] != "" {
+			attributes = append(attributes, attribute)
+		}
+	}
+	for _, value := range f.Exclude {
+		attributes = append(attributes, [2]string{"exclude", value})
+	}
+	for _, value := range f.Post {
+		attributes = append(attributes, [2]string{"post", value})
+	}
+	for _, value := range f.PHP {
+		attributes = append(attributes, [2]string{"php", value})
+	}
+	if len(f.Fallback) > 0 {
+		attributes = append(attributes, [2]string{"fallback", strings.Join(f.Fallback, ",")})
+	}
+
+	for _, attribute := range attributes {
+		if err := component.setAttribute(attribute[0], attribute[1], true); err != nil {
+			return component, fmt.Errorf("invalid component %q: %w", f.Name, err)
+		}
+	}
+
+	if err := component.Validate(); err != nil {
+		return component, fmt.Errorf("invalid component %q: %w", f.Name, err)
+	}
+
+	return component, nil
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadStructuredConfig(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `mediawiki:
+  version: 1.43.1
+extensions:
+  - name: VisualEditor
+  - name: WikiEditor
+    version: REL1_42
+    required: true
+skins:
+  - name: Citizen
+    distributor: git
+    repository: https://github.com/example/skin.git
+    version: main
+    exclude: [tests]
+`,
+		"config.toml": `[mediawiki]
+version = "1.43.1"
+
+[[extensions]]
+name = "VisualEditor"
+
+[[extensions]]
+name = "WikiEditor"
+version = "REL1_42"
+required = true
+
+[[skins]]
+name = "Citizen"
+distributor = "git"
+repository = "https://github.com/example/skin.git"
+version = "main"
+exclude = ["tests"]
+`,
+		"config.json": `{
+  "mediawiki": {"version": "1.43.1"},
+  "extensions": [
+    {"name": "VisualEditor"},
+    {"name": "WikiEditor", "version": "REL1_42", "required": true}
+  ],
+  "skins": [
+    {"name": "Citizen", "distributor": "git", "repository": "https://github.com/example/skin.git", "version": "main", "exclude": ["tests"]}
+  ]
+}`,
+	}
+
+	for filename, content := range files {
+		t.Run(filename, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), filename)
+			if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+				t.Fatalf("Failed to create test config file: %v", err)
+			}
+
+			config, err := LoadConfig(configPath)
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+
+			if config.MediaWiki.Version != "1.43.1" {
+				t.Errorf("Expected MediaWiki version 1.43.1, got %s", config.MediaWiki.Version)
+			}
+
+			if len(config.Extensions) != 2 || config.Extensions[0].Name != "VisualEditor" || config.Extensions[0].Distributor != "extdist" {
+				t.Fatalf("Extensions not parsed correctly: %+v", config.Extensions)
+			}
+
+			if ext := config.Extensions[1]; ext.Name != "WikiEditor" || ext.Version != "REL1_42" || !ext.Required {
+				t.Errorf("Second extension not parsed correctly: %+v", ext)
+			}
+
+			if len(config.Skins) != 1 {
+				t.Fatalf("Expected 1 skin, got %d", len(config.Skins))
+			}
+
+			skin := config.Skins[0]
+			if skin.Distributor != "git" || skin.Name != "https://github.com/example/skin.git" || skin.Dir != "Citizen" ||
+				skin.Version != "main" || len(skin.Exclude) != 1 || skin.Exclude[0] != "tests" {
+				t.Errorf("Skin not parsed correctly: %+v", skin)
+			}
+		})
+	}
+}
+
+func TestYAMLComponentLines(t *testing.T) {
+	lines := yamlComponentLines([]byte(`mediawiki:
+  version: 1.43.1
+extensions:
+  - name: Math
+  - name: Cite
+profiles:
+  staging:
+    skins:
+      - name: Citizen
+`))
+
+	if got := lines["extensions"]; len(got) != 2 || got[0] != 4 || got[1] != 5 {
+		t.Errorf("Expected the extensions on lines 4 and 5, got %v", got)
+	}
+	if got := lines["profiles.staging.skins"]; len(got) != 1 || got[0] != 9 {
+		t.Errorf("Expected the staging skin on line 9, got %v", got)
+	}
+}
+
+func TestLoadStructuredConfigUnknownKey(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "mediawiki:\n  version: 1.43.1\n  flavour: vanilla\n",
+		"config.toml": "[mediawiki]\nversion = \"1.43.1\"\nflavour = \"vanilla\"\n",
+		"config.json": `{"mediawiki": {"version": "1.43.1", "flavour": "vanilla"}}`,
+	}
+
+	for filename, content := range files {
+		configPath := filepath.Join(t.TempDir(), filename)
+		if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		if _, err := LoadConfig(configPath); err == nil {
+			t.Errorf("Expected error for unknown key in %s, got nil", filename)
+		}
+	}
+}
+
+func TestLoadSampleYAMLConfig(t *testing.T) {
+	config, err := LoadConfig(filepath.Join("..", "..", "config-sample.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load sample config: %v", err)
+	}
+
+	if len(config.Extensions) != 6 || len(config.Skins) != 3 {
+		t.Errorf("Expected 6 extensions and 3 skins, got %d and %d", len(config.Extensions), len(config.Skins))
+	}
+
+	staging, err := config.WithProfile("staging")
+	if err != nil {
+		t.Fatalf("Failed to apply staging profile: %v", err)
+	}
+
+	if staging.MediaWiki.Version != "1.44.0" || len(staging.Extensions) != 6 || staging.Extensions[3].Version != "master" {
+		t.Errorf("Staging profile not applied correctly: %+v", staging)
+	}
+}
+
+func TestLoadStructuredConfigAttributeOrder(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := "mediawiki:\n  version: 1.43.1\nextensions:\n  - name: Math\n    dir: ../Math\n    sha256: nothex\n    auth: nowhere\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	// With several invalid attributes, the first one in a fixed order is reported every time
+	_, first := LoadConfig(configPath)
+	if first == nil || !strings.Contains(first.Error(), "dir") {
+		t.Fatalf("Expected the invalid dir to be reported, got %v", first)
+	}
+	for range 20 {
+		if _, err := LoadConfig(configPath); err == nil || err.Error() != first.Error() {
+			t.Fatalf("Expected the same error on every load, got %v and %v", first, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// HTTPConfig configures the HTTP client used for every download and API request
+type HTTPConfig struct {
+	// Proxy is the proxy URL, HTTP_PROXY and HTTPS_PROXY from the environment if empty
+	Proxy string
+	// NoProxy lists hosts, domains, IP addresses and CIDR ranges that bypass the proxy
+	NoProxy []string
+	// CAFiles are PEM files with certificate authorities trusted in addition to the system ones
+	CAFiles []string
+	// ClientCert and ClientKey are PEM files of a client certificate for mutual TLS
+	ClientCert string
+	ClientKey  string
+	// UserAgentSuffix is appended to the User-Agent header
+	UserAgentSuffix string
+	// RateLimit is the number of requests per second to every host, and Burst the number of
+	// requests that may be sent at once before it applies
+	RateLimit float64
+	Burst     int
+	// MaxConcurrent caps the requests in flight at once
+	MaxConcurrent int
+	// MaxRetries is how often a request answered with 429 or 503 is retried after its Retry-After delay
+	MaxRetries int
+}
+
+// Override returns a copy of the settings with every non-empty setting of other replacing the one in h
+func (h HTTPConfig) Override(other HTTPConfig) HTTPConfig {
+	for _, pair := range []struct{ target, value *string }{
+		{&h.Proxy, &other.Proxy},
+		{&h.ClientCert, &other.ClientCert},
+		{&h.ClientKey, &other.ClientKey},
+		{&h.UserAgentSuffix, &other.UserAgentSuffix},
+	} {
+		if *pair.value != "" {
+			*pair.target = *pair.value
+		}
+	}
+	if len(other.NoProxy) > 0 {
+		h.NoProxy = other.NoProxy
+	}
+	if len(other.CAFiles) > 0 {
+		h.CAFiles = other.CAFiles
+	}
+	if other.RateLimit != 0 {
+		h.RateLimit = other.RateLimit
+	}
+	for _, pair := range []struct{ target, value *int }{
+		{&h.Burst, &other.Burst},
+		{&h.MaxConcurrent, &other.MaxConcurrent},
+		{&h.MaxRetries, &other.MaxRetries},
+	} {
+		if *pair.value != 0 {
+			*pair.target = *pair.value
+		}
+	}
+	return h
+}
+
+// Check reports a malformed proxy URL, an incomplete client certificate and a negative burst
+func (h HTTPConfig) Check() error {
+	if h.Proxy != "" {
+		parsed, err := url.Parse(h.Proxy)
+		if err != nil || parsed.Host == "" {
+			return fmt.Errorf("invalid proxy %q (expected a URL like http://proxy.example:3128)", h.Proxy)
+		}
+		switch parsed.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("unsupported proxy scheme %q (expected http, https or socks5)", parsed.Scheme)
+		}
+	}
+
+	if (h.ClientCert == "") != (h.ClientKey == "") {
+		return fmt.Errorf("client_cert and client_key must be set together")
+	}
+	if h.Burst < 0 {
+		return fmt.Errorf("burst must not be negative")
+	}
+	return nil
+}
+
+// parseHTTPFromINI reads the [http] section. no_proxy is comma-separated, and ca_file may be
+// repeated to trust several files. Unset limits are left at zero to use the defaults.
+func parseHTTPFromINI(ini *SimpleINI) (HTTPConfig, error) {
+	h := HTTPConfig{
+		Proxy:           ini.GetFirstValue("http", "proxy"),
+		CAFiles:         ini.GetValues("http", "ca_file"),
+		ClientCert:      ini.GetFirstValue("http", "client_cert"),
+		ClientKey:       ini.GetFirstValue("http", "client_key"),
+		UserAgentSuffix: ini.GetFirstValue("http", "user_agent_suffix"),
+	}
+	for _, host := range strings.Split(ini.GetFirstValue("http", "no_proxy"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			h.NoProxy = append(h.NoProxy, host)
+		}
+	}
+
+	var err error
+	if h.RateLimit, err = parseRate(ini.GetFirstValue("http", "rate_limit")); err != nil {
+		return h, fmt.Errorf("invalid [http] rate_limit: %w", err)
+	}
+	for _, pair := range []struct {
+		key   string
+		value *int
+	}{
+		{"burst", &h.Burst},
+		{"max_concurrent", &h.MaxConcurrent},
+		{"max_retries", &h.MaxRetries},
+	} {
+		value := ini.GetFirstValue("http", pair.key)
+		if value == "" {
+			continue
+		}
+		if *pair.value, err = strconv.Atoi(value); err != nil {
+			return h, fmt.Errorf("invalid [http] %s %q (expected a whole number)", pair.key, value)
+		}
+	}
+
+	if err := h.Check(); err != nil {
+		return h, fmt.Errorf("invalid [http]: %w", err)
+	}
+	return h, nil
+}
+
+// parseRate parses a number of requests per second; an empty value is 0
+func parseRate(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number of requests per second", value)
+	}
+	return rate, nil
+}
@@ -4,33 +4,83 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 )
 
-// SimpleINI represents a simple INI file structure that supports duplicate keys
+// SimpleINI represents a simple INI file structure that supports duplicate keys.
+// Sections, keys and values keep the order in which they appear in the file.
 type SimpleINI struct {
-	sections map[string]map[string][]string
+	sections []*iniSection
+	index    map[string]*iniSection
+	position int // number of sections and entries read so far
 }
 
+// iniSection holds the entries of a single section in file order
+type iniSection struct {
+	name     string
+	file     string
+	line     int
+	position int
+	entries  []Entry
+}
+
+// Entry is a single key-value pair of an INI section
+type Entry struct {
+	Key   string
+	Value string
+	File  string // file the entry was read from, which differs from the loaded file for includes
+	Line  int
+
+	position int // read order across the loaded and included files
+}
+
+// envNamePattern matches environment variable names
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // NewSimpleINI creates a new SimpleINI instance
 func NewSimpleINI() *SimpleINI {
 	return &SimpleINI{
-		sections: make(map[string]map[string][]string),
+		index: make(map[string]*iniSection),
 	}
 }
 
-// LoadINIFile loads an INI file and returns a SimpleINI instance
+// LoadINIFile loads an INI file and returns a SimpleINI instance.
+//
+// An "include=<path>" line, anywhere in the file, loads another INI file at that point;
+// relative paths are resolved against the directory of the including file. Values may
+// reference environment variables as ${VAR} or ${VAR:-default}; use $${ for a literal ${.
 func LoadINIFile(filename string) (*SimpleINI, error) {
+	ini := NewSimpleINI()
+	if err := ini.parseFile(filename, nil); err != nil {
+		return nil, err
+	}
+	return ini, nil
+}
+
+// parseFile parses an INI file into ini. includeStack holds the absolute paths of the files
+// that are currently being parsed and is used to detect include cycles.
+func (ini *SimpleINI) parseFile(filename string, includeStack []string) error {
+	absPath, err := filepath.Abs(filename)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path %s: %w", filename, err)
+	}
+	if slices.Contains(includeStack, absPath) {
+		return fmt.Errorf("include cycle: %s", strings.Join(append(includeStack, absPath), " -> "))
+	}
+	includeStack = append(includeStack, absPath)
+
 	file, err := os.Open(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	ini := NewSimpleINI()
 	scanner := bufio.NewScanner(file)
 
-	currentSection := ""
+	var currentSection *iniSection
 	lineNumber := 0
 
 	for scanner.Scan() {
@@ -44,62 +94,156 @@ func LoadINIFile(filename string) (*SimpleINI, error) {
 
 		// Check for section headers
 		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-			currentSection = strings.TrimSpace(line[1 : len(line)-1])
-			if ini.sections[currentSection] == nil {
-				ini.sections[currentSection] = make(map[string][]string)
-			}
+			currentSection = ini.section(strings.TrimSpace(line[1:len(line)-1]), filename, lineNumber)
 			continue
 		}
 
-		// Parse key-value pairs
-		if currentSection == "" {
-			return nil, fmt.Errorf("key-value pair found outside of section at line %d", lineNumber)
-		}
-
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid line format at line %d: %s", lineNumber, line)
+			return fmt.Errorf("invalid line format at line %d: %s", lineNumber, line)
 		}
 
 		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
+		value, err := expandEnv(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return fmt.Errorf("%w at line %d", err, lineNumber)
+		}
 
-		// Add value to the slice for this key
-		ini.sections[currentSection][key] = append(ini.sections[currentSection][key], value)
+		// Include another file; the current section continues afterwards
+		if key == "include" {
+			includePath := value
+			if !filepath.IsAbs(includePath) {
+				includePath = filepath.Join(filepath.Dir(filename), includePath)
+			}
+			if err := ini.parseFile(includePath, includeStack); err != nil {
+				return fmt.Errorf("failed to include %s at line %d: %w", value, lineNumber, err)
+			}
+			continue
+		}
+
+		// Parse key-value pairs
+		if currentSection == nil {
+			return fmt.Errorf("key-value pair found outside of section at line %d", lineNumber)
+		}
+
+		// Add value to the entries of this section
+		ini.position++
+		currentSection.entries = append(currentSection.entries, Entry{
+			Key:      key,
+			Value:    value,
+			File:     filename,
+			Line:     lineNumber,
+			position: ini.position,
+		})
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+		return fmt.Errorf("error reading file: %w", err)
 	}
 
-	return ini, nil
+	return nil
 }
 
-// GetSection returns all key-value pairs for a given section
-func (ini *SimpleINI) GetSection(sectionName string) map[string][]string {
-	if section, exists := ini.sections[sectionName]; exists {
+// expandEnv replaces ${VAR} and ${VAR:-default} references with environment variables.
+// A variable without a default must be set; $${ produces a literal ${.
+func expandEnv(value string) (string, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+
+	var result strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			result.WriteString(value)
+			return result.String(), nil
+		}
+
+		// Escaped reference
+		if start > 0 && value[start-1] == '$' {
+			result.WriteString(value[:start])
+			result.WriteString("{")
+			value = value[start+2:]
+			continue
+		}
+
+		end := strings.Index(value[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable reference %q", value[start:])
+		}
+
+		result.WriteString(value[:start])
+		reference := value[start+2 : start+end]
+		name, fallback, hasDefault := strings.Cut(reference, ":-")
+		if !envNamePattern.MatchString(name) {
+			return "", fmt.Errorf("invalid variable reference ${%s}", reference)
+		}
+
+		if envValue, set := os.LookupEnv(name); set && (envValue != "" || !hasDefault) {
+			result.WriteString(envValue)
+		} else if hasDefault {
+			result.WriteString(fallback)
+		} else {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+
+		value = value[start+end+1:]
+	}
+}
+
+// section returns the named section, creating it if it does not exist yet
+func (ini *SimpleINI) section(name, file string, line int) *iniSection {
+	if section, exists := ini.index[name]; exists {
 		return section
 	}
-	return make(map[string][]string)
+
+	ini.position++
+	section := &iniSection{name: name, file: file, line: line, position: ini.position}
+	ini.sections = append(ini.sections, section)
+	ini.index[name] = section
+	return section
 }
 
-// GetSectionKeys returns all keys in a section
+// GetEntries returns all key-value pairs of a section in file order
+func (ini *SimpleINI) GetEntries(sectionName string) []Entry {
+	if section, exists := ini.index[sectionName]; exists {
+		return section.entries
+	}
+	return []Entry{}
+}
+
+// GetSection returns all key-value pairs for a given section.
+// The map does not preserve order; use GetEntries or GetSectionKeys to iterate in file order.
+func (ini *SimpleINI) GetSection(sectionName string) map[string][]string {
+	section := make(map[string][]string)
+	for _, entry := range ini.GetEntries(sectionName) {
+		section[entry.Key] = append(section[entry.Key], entry.Value)
+	}
+	return section
+}
+
+// GetSectionKeys returns all keys in a section in order of their first appearance
 func (ini *SimpleINI) GetSectionKeys(sectionName string) []string {
-	section := ini.GetSection(sectionName)
-	keys := make([]string, 0, len(section))
-	for key := range section {
-		keys = append(keys, key)
+	var keys []string
+	seen := make(map[string]bool)
+	for _, entry := range ini.GetEntries(sectionName) {
+		if !seen[entry.Key] {
+			seen[entry.Key] = true
+			keys = append(keys, entry.Key)
+		}
 	}
 	return keys
 }
 
-// GetValues returns all values for a specific key in a section
+// GetValues returns all values for a specific key in a section in file order
 func (ini *SimpleINI) GetValues(sectionName, key string) []string {
-	section := ini.GetSection(sectionName)
-	if values, exists := section[key]; exists {
-		return values
+	values := []string{}
+	for _, entry := range ini.GetEntries(sectionName) {
+		if entry.Key == key {
+			values = append(values, entry.Value)
+		}
 	}
-	return []string{}
+	return values
 }
 
 // GetFirstValue returns the first value for a specific key in a section
@@ -110,3 +254,36 @@ func (ini *SimpleINI) GetFirstValue(sectionName, key string) string {
 	}
 	return ""
 }
+
+// SectionNames returns the names of all sections in file order
+func (ini *SimpleINI) SectionNames() []string {
+	names := make([]string, 0, len(ini.sections))
+	for _, section := range ini.sections {
+		names = append(names, section.name)
+	}
+	return names
+}
+
+// SectionLine returns the line number of a section header, or 0 if the section does not exist
+func (ini *SimpleINI) SectionLine(sectionName string) int {
+	if section, exists := ini.index[sectionName]; exists {
+		return section.line
+	}
+	return 0
+}
+
+// sectionPosition returns the read order of a section header
+func (ini *SimpleINI) sectionPosition(sectionName string) int {
+	if section, exists := ini.index[sectionName]; exists {
+		return section.position
+	}
+	return 0
+}
+
+// SectionFile returns the file a section was first declared in, or "" if the section does not exist
+func (ini *SimpleINI) SectionFile(sectionName string) string {
+	if section, exists := ini.index[sectionName]; exists {
+		return section.file
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSimpleINIPreservesOrder(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "test.ini")
+	content := `[skins]
+git=https://github.com/example/first.git
+extdist=Vector
+git=https://github.com/example/second.git
+
+[mediawiki]
+version=1.43.1
+
+[extensions]
+extdist=Cite
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	ini, err := LoadINIFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load INI file: %v", err)
+	}
+
+	if names := ini.SectionNames(); !reflect.DeepEqual(names, []string{"skins", "mediawiki", "extensions"}) {
+		t.Errorf("Sections not in file order: %v", names)
+	}
+
+	if keys := ini.GetSectionKeys("skins"); !reflect.DeepEqual(keys, []string{"git", "extdist"}) {
+		t.Errorf("Keys not in file order: %v", keys)
+	}
+
+	expected := []Entry{
+		{Key: "git", Value: "https://github.com/example/first.git", File: configPath, Line: 2, position: 2},
+		{Key: "extdist", Value: "Vector", File: configPath, Line: 3, position: 3},
+		{Key: "git", Value: "https://github.com/example/second.git", File: configPath, Line: 4, position: 4},
+	}
+	if entries := ini.GetEntries("skins"); !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Entries not in file order: %+v", entries)
+	}
+
+	if ini.SectionLine("mediawiki") != 6 {
+		t.Errorf("Expected [mediawiki] on line 6, got %d", ini.SectionLine("mediawiki"))
+	}
+}
+
+func TestLoadINIFileErrors(t *testing.T) {
+	tests := map[string]string{
+		"outside section": "version=1.43.1\n",
+		"missing equals":  "[mediawiki]\nversion\n",
+	}
+
+	for name, content := range tests {
+		configPath := filepath.Join(t.TempDir(), "test.ini")
+		if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+
+		if _, err := LoadINIFile(configPath); err == nil {
+			t.Errorf("Expected error for %s, got nil", name)
+		}
+	}
+}
+
+func TestLoadINIFileInclude(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "shared"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"wiki.ini": `include=shared/common.ini
+
+[extensions]
+extdist=WikiSpecific
+`,
+		"shared/common.ini": `[mediawiki]
+version=1.43.1
+
+[extensions]
+extdist=Cite
+include=more.ini
+extdist=Math
+`,
+		"shared/more.ini": `[skins]
+extdist=Vector
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ini, err := LoadINIFile(filepath.Join(tempDir, "wiki.ini"))
+	if err != nil {
+		t.Fatalf("Failed to load INI file: %v", err)
+	}
+
+	if values := ini.GetValues("extensions", "extdist"); !reflect.DeepEqual(values, []string{"Cite", "Math", "WikiSpecific"}) {
+		t.Errorf("Unexpected extensions: %v", values)
+	}
+
+	if value := ini.GetFirstValue("skins", "extdist"); value != "Vector" {
+		t.Errorf("Expected skin from nested include, got %q", value)
+	}
+
+	entries := ini.GetEntries("extensions")
+	if entries[0].File != filepath.Join(tempDir, "shared/common.ini") || entries[0].Line != 5 {
+		t.Errorf("Expected included entry to record its own file and line, got %s:%d", entries[0].File, entries[0].Line)
+	}
+}
+
+func TestLoadINIFileIncludeCycle(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a.ini"), []byte("include=b.ini\n"), 0o644)
+	os.WriteFile(filepath.Join(tempDir, "b.ini"), []byte("include=a.ini\n"), 0o644)
+
+	_, err := LoadINIFile(filepath.Join(tempDir, "a.ini"))
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("Expected include cycle error, got %v", err)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("MWU_TEST_TOKEN", "secret")
+	t.Setenv("MWU_TEST_EMPTY", "")
+
+	tests := []struct {
+		input    string
+		expected string
+		hasError bool
+	}{
+		{"plain", "plain", false},
+		{"${MWU_TEST_TOKEN}", "secret", false},
+		{"user:${MWU_TEST_TOKEN}@host", "user:secret@host", false},
+		{"${MWU_TEST_UNSET:-REL1_43}", "REL1_43", false},
+		{"${MWU_TEST_EMPTY:-fallback}", "fallback", false},
+		{"${MWU_TEST_EMPTY}", "", false},
+		{"$${MWU_TEST_TOKEN}", "${MWU_TEST_TOKEN}", false},
+		{"${MWU_TEST_UNSET}", "", true},
+		{"${MWU_TEST_TOKEN", "", true},
+		{"${not valid}", "", true},
+	}
+
+	for _, test := range tests {
+		result, err := expandEnv(test.input)
+		if test.hasError {
+			if err == nil {
+				t.Errorf("Expected error for input %s, but got none", test.input)
+			}
+		} else if err != nil || result != test.expected {
+			t.Errorf("Expected %q for input %s, got %q (error: %v)", test.expected, test.input, result, err)
+		}
+	}
+}
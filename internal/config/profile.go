@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Profile is a named overlay that is merged onto the base configuration, e.g. for a staging wiki.
+//
+// Components in a profile are added to the base configuration, or replace the base component
+// with the same name or installation directory. Removals name base components to drop.
+type Profile struct {
+	MediaWiki        MediaWikiConfig
+	Extensions       []ComponentConfig
+	Skins            []ComponentConfig
+	RemoveExtensions []string
+	RemoveSkins      []string
+}
+
+// profileSectionPattern matches profile sections like [profile:staging] or [profile:staging extensions]
+var profileSectionPattern = regexp.MustCompile(`^profile:([\w.-]+)(?:\s+(extensions|skins))?$`)
+
+// IsProfileSection reports whether sectionName is a profile section like [profile:staging]
+func IsProfileSection(sectionName string) bool {
+	return profileSectionPattern.MatchString(sectionName)
+}
+
+// ProfileNames returns the names of all profiles, sorted
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithProfile returns a copy of the configuration with the named profile merged onto it
+func (c *Config) WithProfile(name string) (*Config, error) {
+	profile, exists := c.Profiles[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+
+	merged := *c
+	if profile.MediaWiki.Version != "" {
+		merged.MediaWiki.Version = profile.MediaWiki.Version
+	}
+	if profile.MediaWiki.SettingsFile != "" {
+		merged.MediaWiki.SettingsFile = profile.MediaWiki.SettingsFile
+	}
+	if profile.MediaWiki.PHP != "" {
+		merged.MediaWiki.PHP = profile.MediaWiki.PHP
+	}
+	if len(profile.MediaWiki.Fallback) > 0 {
+		merged.MediaWiki.Fallback = profile.MediaWiki.Fallback
+	}
+
+	var err error
+	merged.Extensions, err = mergeComponents(c.Extensions, profile.Extensions, profile.RemoveExtensions)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+
+	merged.Skins, err = mergeComponents(c.Skins, profile.Skins, profile.RemoveSkins)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+
+	return &merged, nil
+}
+
+// mergeComponents removes the named components from base, then replaces matching components
+// with the overlay versions in place and appends the rest
+func mergeComponents(base, overlay []ComponentConfig, remove []string) ([]ComponentConfig, error) {
+	merged := make([]ComponentConfig, 0, len(base)+len(overlay))
+
+	removed := make(map[string]bool)
+	for _, component := range base {
+		index := matchComponent(remove, component)
+		if index < 0 {
+			merged = append(merged, component)
+			continue
+		}
+		removed[strings.ToLower(remove[index])] = true
+	}
+
+	for _, name := range remove {
+		if !removed[strings.ToLower(name)] {
+			return nil, fmt.Errorf("cannot remove %s: not in the base configuration", name)
+		}
+	}
+
+	for _, component := range overlay {
+		replaced := false
+		for i := range merged {
+			if sameComponent(merged[i], component) {
+				merged[i] = component
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, component)
+		}
+	}
+
+	return merged, nil
+}
+
+// matchComponent returns the index of the first name that refers to component, or -1
+func matchComponent(names []string, component ComponentConfig) int {
+	for i, name := range names {
+		if strings.EqualFold(name, component.Name) || strings.EqualFold(name, component.DirName()) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameComponent reports whether two components refer to the same extension or skin,
+// either by name or by installation directory
+func sameComponent(a, b ComponentConfig) bool {
+	return strings.EqualFold(a.Name, b.Name) || strings.EqualFold(a.DirName(), b.DirName())
+}
+
+// parseProfilesFromINI parses all [profile:<name>], [profile:<name> extensions] and
+// [profile:<name> skins] sections
+func parseProfilesFromINI(ini *SimpleINI) (map[string]*Profile, error) {
+	profiles := make(map[string]*Profile)
+
+	for _, sectionName := range ini.SectionNames() {
+		matches := profileSectionPattern.FindStringSubmatch(sectionName)
+		if matches == nil {
+			continue
+		}
+
+		profile := profiles[matches[1]]
+		if profile == nil {
+			profile = &Profile{}
+			profiles[matches[1]] = profile
+		}
+
+		switch matches[2] {
+		case "":
+			profile.MediaWiki.Version = ini.GetFirstValue(sectionName, "version")
+			profile.MediaWiki.SettingsFile = ini.GetFirstValue(sectionName, "settings_file")
+			profile.MediaWiki.PHP = ini.GetFirstValue(sectionName, "php")
+			if value := ini.GetFirstValue(sectionName, "fallback"); value != "" {
+				fallback, err := ParseFallback(value, false)
+				if err != nil {
+					return nil, fmt.Errorf("invalid fallback in [%s]: %w", sectionName, err)
+				}
+				profile.MediaWiki.Fallback = fallback
+			}
+
+		case "extensions", "skins":
+			componentType, components, removals := TypeExtension, &profile.Extensions, &profile.RemoveExtensions
+			if matches[2] == "skins" {
+				componentType, components, removals = TypeSkin, &profile.Skins, &profile.RemoveSkins
+			}
+
+			for _, entry := range ini.GetEntries(sectionName) {
+				if entry.Key == "remove" {
+					*removals = append(*removals, entry.Value)
+					continue
+				}
+
+				component, err := parseComponentEntry(entry, componentType)
+				if err != nil {
+					return nil, err
+				}
+				*components = append(*components, component)
+			}
+		}
+	}
+
+	return profiles, nil
+}
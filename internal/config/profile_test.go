@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithProfile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "test.ini")
+	content := `[mediawiki]
+version=1.43.1
+
+[extensions]
+extdist=Cite
+extdist=Math
+git=https://github.com/example/Private.git|main
+
+[skins]
+extdist=Vector
+
+[profile:staging]
+version=1.44.0-rc.0
+
+[profile:staging extensions]
+remove=Cite
+extdist=Math|master
+git=https://github.com/example/Private.git|develop
+extdist=DebugTools
+
+[profile:staging skins]
+extdist=Timeless
+
+[profile:production extensions]
+remove=Private
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	base, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if names := base.ProfileNames(); strings.Join(names, ",") != "production,staging" {
+		t.Fatalf("Expected profiles production and staging, got %v", names)
+	}
+
+	staging, err := base.WithProfile("staging")
+	if err != nil {
+		t.Fatalf("Failed to apply profile: %v", err)
+	}
+
+	if staging.MediaWiki.Version != "1.44.0-rc.0" {
+		t.Errorf("Expected MediaWiki version 1.44.0-rc.0, got %s", staging.MediaWiki.Version)
+	}
+
+	// Overrides keep the position of the base component, additions are appended
+	expected := []string{"Math@master", "https://github.com/example/Private.git@develop", "DebugTools@"}
+	if len(staging.Extensions) != len(expected) {
+		t.Fatalf("Expected %d extensions, got %+v", len(expected), staging.Extensions)
+	}
+	for i, want := range expected {
+		if got := staging.Extensions[i].Name + "@" + staging.Extensions[i].Version; got != want {
+			t.Errorf("Extension %d: expected %s, got %s", i, want, got)
+		}
+	}
+
+	if len(staging.Skins) != 2 || staging.Skins[1].Name != "Timeless" {
+		t.Errorf("Expected Timeless to be added to the skins, got %+v", staging.Skins)
+	}
+
+	// The base configuration is not modified
+	if base.MediaWiki.Version != "1.43.1" || len(base.Extensions) != 3 || base.Extensions[1].Version != "" {
+		t.Errorf("Base configuration was modified: %+v", base)
+	}
+
+	// Components are removed by their installation directory as well as their name
+	production, err := base.WithProfile("production")
+	if err != nil {
+		t.Fatalf("Failed to apply profile: %v", err)
+	}
+	if production.MediaWiki.Version != "1.43.1" || len(production.Extensions) != 2 {
+		t.Errorf("Unexpected production configuration: %+v", production)
+	}
+}
+
+func TestWithProfileErrors(t *testing.T) {
+	base := &Config{
+		Extensions: []ComponentConfig{{Type: TypeExtension, Distributor: "extdist", Name: "Cite"}},
+		Profiles: map[string]*Profile{
+			"staging": {RemoveExtensions: []string{"Math"}},
+		},
+	}
+
+	if _, err := base.WithProfile("missing"); err == nil || !strings.Contains(err.Error(), "available: staging") {
+		t.Errorf("Expected unknown profile error listing the profiles, got %v", err)
+	}
+
+	if _, err := base.WithProfile("staging"); err == nil || !strings.Contains(err.Error(), "cannot remove Math") {
+		t.Errorf("Expected error for removing a missing component, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// NoSource disables an optional upstream endpoint, like the ExtDist API
+const NoSource = "none"
+
+// SourcesConfig overrides the upstream endpoints. Every list is an ordered set of mirrors that
+// are tried in turn; an empty list uses the official endpoint. Mirrors are http(s) or file://
+// URLs, or local directories laid out like the upstream server.
+type SourcesConfig struct {
+	// Releases are directories of MediaWiki releases, like https://releases.wikimedia.org/mediawiki/
+	Releases []string
+	// ExtDist are directories of ExtDist archives, like https://extdist.wmflabs.org/dist/
+	ExtDist []string
+	// ExtDistAPI are MediaWiki APIs serving ExtDist metadata, or "none" to only use the ExtDist directories
+	ExtDistAPI []string
+	// Composer are directories with the composer.json of every MediaWiki release at <version>/composer.json
+	Composer []string
+	// RateLimits override the [http] rate_limit for the hosts of a source, keyed by source like
+	// "extdist"; set with <source>_rate_limit
+	RateLimits map[string]float64
+}
+
+// sourceKeys are the keys of the [sources] section
+var sourceKeys = []string{"releases", "extdist", "extdist_api", "composer"}
+
+// Override returns a copy of the sources with every non-empty list of other replacing the list in s
+func (s SourcesConfig) Override(other SourcesConfig) SourcesConfig {
+	for _, pair := range []struct{ target, value *[]string }{
+		{&s.Releases, &other.Releases},
+		{&s.ExtDist, &other.ExtDist},
+		{&s.ExtDistAPI, &other.ExtDistAPI},
+		{&s.Composer, &other.Composer},
+	} {
+		if len(*pair.value) > 0 {
+			*pair.target = *pair.value
+		}
+	}
+	if len(other.RateLimits) > 0 {
+		s.RateLimits = maps.Clone(s.RateLimits)
+		if s.RateLimits == nil {
+			s.RateLimits = make(map[string]float64, len(other.RateLimits))
+		}
+		maps.Copy(s.RateLimits, other.RateLimits)
+	}
+	return s
+}
+
+// Check reports malformed mirror locations and rate limits of unknown sources
+func (s SourcesConfig) Check() error {
+	for key := range s.RateLimits {
+		if !slices.Contains(sourceKeys, key) {
+			return fmt.Errorf("rate limit of unknown source %q (expected one of %s)", key, strings.Join(sourceKeys, ", "))
+		}
+	}
+
+	for _, key := range sourceKeys {
+		mirrors := *s.list(key)
+		for _, mirror := range mirrors {
+			if mirror == NoSource && key == "extdist_api" && len(mirrors) == 1 {
+				continue
+			}
+			if !isMirror(mirror) {
+				return fmt.Errorf("invalid %s source %q (expected an http(s) or file:// URL or an absolute directory)", key, mirror)
+			}
+		}
+	}
+	return nil
+}
+
+// list returns the mirror list for a key of the [sources] section
+func (s *SourcesConfig) list(key string) *[]string {
+	switch key {
+	case "releases":
+		return &s.Releases
+	case "extdist":
+		return &s.ExtDist
+	case "extdist_api":
+		return &s.ExtDistAPI
+	default:
+		return &s.Composer
+	}
+}
+
+// parseSourcesFromINI reads the [sources] section, where every key may be repeated to list mirrors in order
+func parseSourcesFromINI(ini *SimpleINI) (SourcesConfig, error) {
+	var sources SourcesConfig
+	for _, key := range sourceKeys {
+		*sources.list(key) = ini.GetValues("sources", key)
+
+		rate, err := parseRate(ini.GetFirstValue("sources", key+"_rate_limit"))
+		if err != nil {
+			return sources, fmt.Errorf("invalid [sources] %s_rate_limit: %w", key, err)
+		}
+		if rate != 0 {
+			if sources.RateLimits == nil {
+				sources.RateLimits = make(map[string]float64)
+			}
+			sources.RateLimits[key] = rate
+		}
+	}
+
+	if err := sources.Check(); err != nil {
+		return sources, fmt.Errorf("invalid [sources]: %w", err)
+	}
+	return sources, nil
+}
+
+// isMirror reports whether value is an http(s) or file:// URL or an absolute local path
+func isMirror(value string) bool {
+	if filepath.IsAbs(value) {
+		return true
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	switch parsed.Scheme {
+	case "http", "https":
+		return parsed.Host != ""
+	case "file":
+		return parsed.Path != ""
+	}
+	return false
+}
@@ -0,0 +1,294 @@
+package detect
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/manifest"
+)
+
+var (
+	// mwVersionPattern matches the MW_VERSION constant in includes/Defines.php
+	mwVersionPattern = regexp.MustCompile(`define\(\s*['"]MW_VERSION['"]\s*,\s*['"]([^'"]+)['"]`)
+	// wgVersionPattern matches $wgVersion in includes/DefaultSettings.php of MediaWiki before 1.35
+	wgVersionPattern = regexp.MustCompile(`\$wgVersion\s*=\s*['"]([^'"]+)['"]`)
+	// wfLoadPattern matches wfLoadExtension(s) and wfLoadSkin(s) calls in LocalSettings.php
+	wfLoadPattern = regexp.MustCompile(`(?s)wfLoad(Extension|Skin)(s?)\s*\((.*?)\)\s*;`)
+	// legacyLoadPattern matches require_once of a legacy PHP entry point in LocalSettings.php
+	legacyLoadPattern = regexp.MustCompile(`(?:require|include)(?:_once)?\s*\(?\s*["'][^"']*\b(extensions|skins)/([^/"']+)/`)
+	// quotedPattern matches a single- or double-quoted PHP string
+	quotedPattern = regexp.MustCompile(`['"]([^'"]+)['"]`)
+	// blockCommentPattern matches PHP block comments
+	blockCommentPattern = regexp.MustCompile(`(?s)/\*.*?\*/`)
+)
+
+// Installation describes an existing MediaWiki installation
+type Installation struct {
+	Root       string
+	Version    string // MediaWiki core version, e.g. 1.43.1
+	Extensions []Component
+	Skins      []Component
+
+	// HasLocalSettings reports whether LocalSettings.php was found; if not, Loaded is never set
+	HasLocalSettings bool
+	// Missing lists components that LocalSettings.php loads but that are not installed, e.g. "extension Math"
+	Missing []string
+}
+
+// Component is an installed extension or skin
+type Component struct {
+	Type    string // config.TypeExtension or config.TypeSkin
+	Dir     string // directory name below extensions/ or skins/
+	Name    string // name from extension.json or skin.json
+	Version string // version from extension.json or skin.json
+	URL     string // homepage from extension.json or skin.json
+	Remote  string // Git remote URL, if the directory is a Git checkout
+	Ref     string // checked out Git branch, or commit SHA if detached
+	Loaded  bool   // whether LocalSettings.php loads the component
+}
+
+// Detect inspects the MediaWiki installation in root
+func Detect(root string) (*Installation, error) {
+	version, err := coreVersion(root)
+	if err != nil {
+		return nil, err
+	}
+
+	installation := &Installation{Root: root, Version: version}
+
+	installation.Extensions, err = detectComponents(filepath.Join(root, "extensions"), config.TypeExtension)
+	if err != nil {
+		return nil, err
+	}
+
+	installation.Skins, err = detectComponents(filepath.Join(root, "skins"), config.TypeSkin)
+	if err != nil {
+		return nil, err
+	}
+
+	localSettings, err := os.ReadFile(filepath.Join(root, "LocalSettings.php"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read LocalSettings.php: %w", err)
+	}
+	if err == nil {
+		installation.HasLocalSettings = true
+		installation.crossCheck(LoadedComponents(string(localSettings)))
+	}
+
+	return installation, nil
+}
+
+// coreVersion reads the MediaWiki version from includes/Defines.php, or from
+// includes/DefaultSettings.php for versions before 1.35
+func coreVersion(root string) (string, error) {
+	sources := []struct {
+		file    string
+		pattern *regexp.Regexp
+	}{
+		{filepath.Join(root, "includes", "Defines.php"), mwVersionPattern},
+		{filepath.Join(root, "includes", "DefaultSettings.php"), wgVersionPattern},
+	}
+
+	for _, source := range sources {
+		content, err := os.ReadFile(source.file)
+		if err != nil {
+			continue
+		}
+		if matches := source.pattern.FindSubmatch(content); matches != nil {
+			return string(matches[1]), nil
+		}
+	}
+
+	return "", fmt.Errorf("%s does not look like a MediaWiki installation: no version found in includes/Defines.php or includes/DefaultSettings.php", root)
+}
+
+// detectComponents lists the extensions or skins installed in dir
+func detectComponents(dir, componentType string) ([]Component, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	var components []Component
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		componentDir := filepath.Join(dir, entry.Name())
+		component := Component{Type: componentType, Dir: entry.Name()}
+
+		if err := component.readManifest(componentDir); err != nil {
+			return nil, err
+		}
+		component.Remote, component.Ref = gitCheckout(componentDir)
+
+		components = append(components, component)
+	}
+
+	return components, nil
+}
+
+// readManifest reads the name, version and homepage from extension.json or skin.json, if present
+func (c *Component) readManifest(dir string) error {
+	m, err := manifest.Read(dir, c.Type)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", c.Type, c.Dir, err)
+	}
+	if m != nil {
+		c.Name, c.Version, c.URL = m.Name, m.Version, m.URL
+	}
+	return nil
+}
+
+// gitCheckout returns the remote URL and the checked out branch or commit of a Git checkout,
+// or empty strings if dir is not one. Credentials in the remote URL are removed.
+func gitCheckout(dir string) (remote, ref string) {
+	gitDir := filepath.Join(dir, ".git")
+	info, err := os.Stat(gitDir)
+	if err != nil {
+		return "", ""
+	}
+
+	// Submodules and worktrees have a .git file pointing to the actual Git directory
+	if !info.IsDir() {
+		content, err := os.ReadFile(gitDir)
+		if err != nil {
+			return "", ""
+		}
+		path, found := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+		if !found {
+			return "", ""
+		}
+		gitDir = strings.TrimSpace(path)
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(dir, gitDir)
+		}
+	}
+
+	remote = redactURL(gitRemote(filepath.Join(gitDir, "config")))
+	if remote == "" {
+		return "", ""
+	}
+
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return remote, ""
+	}
+	ref = strings.TrimSpace(string(head))
+	if branch, found := strings.CutPrefix(ref, "ref: refs/heads/"); found {
+		ref = branch
+	}
+
+	return remote, ref
+}
+
+// gitRemote returns the URL of the origin remote in a Git config file, or of the first remote
+// if there is no origin
+func gitRemote(configPath string) string {
+	file, err := os.Open(configPath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	var section, first string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = line
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found || strings.TrimSpace(key) != "url" || !strings.HasPrefix(section, "[remote ") {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if section == `[remote "origin"]` {
+			return value
+		}
+		if first == "" {
+			first = value
+		}
+	}
+
+	return first
+}
+
+// redactURL removes credentials from a remote URL, so that they are not written to the configuration
+func redactURL(remote string) string {
+	parsed, err := url.Parse(remote)
+	if err != nil || parsed.User == nil || parsed.Scheme == "ssh" {
+		return remote
+	}
+	parsed.User = nil
+	return parsed.String()
+}
+
+// LoadedComponents returns the extensions and skins loaded by LocalSettings.php,
+// keyed by component type and directory name, e.g. "extension/Math"
+func LoadedComponents(localSettings string) map[string]bool {
+	// Drop comments, so that disabled components are not counted
+	localSettings = blockCommentPattern.ReplaceAllString(localSettings, "")
+	var lines []string
+	for _, line := range strings.Split(localSettings, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "//") {
+			lines = append(lines, line)
+		}
+	}
+	localSettings = strings.Join(lines, "\n")
+
+	loaded := make(map[string]bool)
+	for _, matches := range wfLoadPattern.FindAllStringSubmatch(localSettings, -1) {
+		componentType := strings.ToLower(matches[1])
+		names := quotedPattern.FindAllStringSubmatch(matches[3], -1)
+		// wfLoadExtension( 'Name', '/path/to/extension.json' ) takes the name first
+		if matches[2] == "" && len(names) > 1 {
+			names = names[:1]
+		}
+		for _, name := range names {
+			loaded[componentType+"/"+name[1]] = true
+		}
+	}
+
+	for _, matches := range legacyLoadPattern.FindAllStringSubmatch(localSettings, -1) {
+		loaded[strings.TrimSuffix(matches[1], "s")+"/"+matches[2]] = true
+	}
+
+	return loaded
+}
+
+// crossCheck marks the installed components that are loaded and records the loaded
+// components that are not installed
+func (inst *Installation) crossCheck(loaded map[string]bool) {
+	installed := make(map[string]bool)
+	for _, components := range [][]Component{inst.Extensions, inst.Skins} {
+		for i := range components {
+			key := components[i].Type + "/" + components[i].Dir
+			components[i].Loaded = loaded[key]
+			installed[key] = true
+		}
+	}
+
+	for key := range loaded {
+		if !installed[key] {
+			componentType, name, _ := strings.Cut(key, "/")
+			inst.Missing = append(inst.Missing, componentType+" "+name)
+		}
+	}
+	slices.Sort(inst.Missing)
+}
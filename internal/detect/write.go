@@ -0,0 +1,141 @@
+package detect
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/version"
+)
+
+// bundledComponent is an extension or skin shipped in the MediaWiki release tarballs from release
+// since up to, but not including, release until. An empty bound is open.
+type bundledComponent struct {
+	name  string
+	since string
+	until string
+}
+
+// bundledComponents are the extensions and skins shipped in the MediaWiki release tarballs since
+// 1.35, keyed by type. They are updated together with core.
+var bundledComponents = map[string][]bundledComponent{
+	config.TypeExtension: {
+		{"AbuseFilter", "", ""}, {"CategoryTree", "", ""}, {"Cite", "", ""}, {"CiteThisPage", "", ""},
+		{"CodeEditor", "", ""}, {"ConfirmEdit", "", ""}, {"DiscussionTools", "1.41", ""}, {"Echo", "1.41", ""},
+		{"Gadgets", "", ""}, {"ImageMap", "", ""}, {"InputBox", "", ""}, {"Interwiki", "", ""},
+		{"Linter", "1.41", ""}, {"LoginNotify", "1.41", ""}, {"Math", "", ""}, {"MultimediaViewer", "", ""},
+		{"Nuke", "", ""}, {"OATHAuth", "", ""}, {"PageImages", "", ""}, {"ParserFunctions", "", ""},
+		{"PdfHandler", "", ""}, {"Poem", "", ""}, {"Renameuser", "", "1.40"}, {"ReplaceText", "", ""},
+		{"Scribunto", "", ""}, {"SecureLinkFixer", "", ""}, {"SpamBlacklist", "", ""},
+		{"SyntaxHighlight_GeSHi", "", ""}, {"TemplateData", "", ""}, {"TextExtracts", "", ""},
+		{"Thanks", "1.41", ""}, {"TitleBlacklist", "", ""}, {"VisualEditor", "", ""}, {"WikiEditor", "", ""},
+	},
+	config.TypeSkin: {
+		{"MinervaNeue", "", ""}, {"MonoBook", "", ""}, {"Timeless", "", ""}, {"Vector", "", ""},
+	},
+}
+
+// isBundled reports whether the release tarball of mwVersion ships the component. Versions that
+// cannot be parsed are treated as the newest release.
+func isBundled(componentType, dir, mwVersion string) bool {
+	parsed, err := version.Parse(mwVersion)
+	for _, component := range bundledComponents[componentType] {
+		if component.name != dir {
+			continue
+		}
+		if err != nil {
+			return component.until == ""
+		}
+		release := parsed.Release()
+		return (component.since == "" || release.Compare(version.MustParse(component.since)) >= 0) &&
+			(component.until == "" || release.Compare(version.MustParse(component.until)) < 0)
+	}
+	return false
+}
+
+// WriteINI writes an INI configuration for the installation to w.
+//
+// Git checkouts become git= entries pinned to their branch or commit, everything else
+// becomes an extdist= entry that follows the MediaWiki version. Components that are installed
+// but not loaded by LocalSettings.php, and components bundled with MediaWiki, which are updated
+// with core, are written commented out.
+func (inst *Installation) WriteINI(w io.Writer) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "; Generated by mediawiki-updater init from %s\n", inst.Root)
+	fmt.Fprintln(out, "; Review this file before the first update; see README.md for all options.")
+	if !inst.HasLocalSettings {
+		fmt.Fprintln(out, "; LocalSettings.php was not found, so every installed component is listed.")
+	}
+	fmt.Fprintln(out)
+
+	fmt.Fprintln(out, "[mediawiki]")
+	fmt.Fprintf(out, "version=%s\n", inst.Version)
+
+	for _, section := range []struct {
+		name       string
+		components []Component
+	}{
+		{"extensions", inst.Extensions},
+		{"skins", inst.Skins},
+	} {
+		fmt.Fprintf(out, "\n[%s]\n", section.name)
+		for _, component := range section.components {
+			inst.writeComponent(out, component)
+		}
+
+		prefix := strings.TrimSuffix(section.name, "s") + " "
+		for _, missing := range inst.Missing {
+			if name, found := strings.CutPrefix(missing, prefix); found {
+				fmt.Fprintf(out, "; LocalSettings.php loads %s, but %s/%s is not installed\n", name, section.name, name)
+			}
+		}
+	}
+
+	return out.Flush()
+}
+
+// writeComponent writes the entry for a single component, preceded by a descriptive comment
+func (inst *Installation) writeComponent(out io.Writer, component Component) {
+	var notes []string
+	if component.Name != "" && component.Name != component.Dir {
+		notes = append(notes, component.Name)
+	}
+	if component.Version != "" {
+		notes = append(notes, "version "+component.Version)
+	}
+	if component.URL != "" {
+		notes = append(notes, component.URL)
+	}
+	if component.Name == "" {
+		notes = append(notes, fmt.Sprintf("no %s.json", component.Type))
+	}
+
+	entry := "extdist=" + component.Dir
+	if component.Remote != "" {
+		entry = "git=" + component.Remote
+		if component.Ref != "" {
+			entry += "|" + component.Ref
+		}
+		if config.RepoName(component.Remote) != component.Dir {
+			entry += "|dir=" + component.Dir
+		}
+	}
+
+	comment := ""
+	if isBundled(component.Type, component.Dir, inst.Version) {
+		notes = append(notes, "bundled with MediaWiki")
+		comment = ";"
+	}
+	if inst.HasLocalSettings && !component.Loaded {
+		notes = append(notes, "not loaded in LocalSettings.php")
+		comment = ";"
+	}
+
+	if len(notes) > 0 {
+		fmt.Fprintf(out, "; %s: %s\n", component.Dir, strings.Join(notes, ", "))
+	}
+	fmt.Fprintf(out, "%s%s\n", comment, entry)
+}
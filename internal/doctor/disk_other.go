@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package doctor
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// freeSpace is not supported on this platform
+func freeSpace(dir string) (uint64, error) {
+	return 0, fmt.Errorf("not supported on %s", runtime.GOOS)
+}
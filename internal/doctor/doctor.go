@@ -0,0 +1,197 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/SKevo18/mediawiki-updater/internal/manifest"
+	"github.com/SKevo18/mediawiki-updater/internal/mediawiki"
+	"github.com/SKevo18/mediawiki-updater/internal/php"
+)
+
+// DefaultRequiredSpace is the free disk space required if the size of an update is not known yet
+const DefaultRequiredSpace = 512 << 20
+
+// Check statuses
+const (
+	StatusOK      = "ok"
+	StatusWarning = "warning"
+	StatusFailed  = "failed"
+)
+
+// Result is the outcome of a single check
+type Result struct {
+	Status  string
+	Message string
+}
+
+// Options describes what to check
+type Options struct {
+	TargetDir string
+	PHPBinary string
+	// PHPRequired fails the checks if PHP cannot be detected, e.g. because PHPBinary was set explicitly
+	PHPRequired bool
+	// PHP is the already detected PHP runtime; if nil, PHPBinary is run to detect it
+	PHP *php.Info
+	// MediaWiki is the MediaWiki version that is going to be installed
+	MediaWiki string
+	// Requirements are the manifests whose PHP requirements must be met, e.g. MediaWiki's composer.json
+	Requirements []*manifest.Manifest
+	// RequiredSpace is the free disk space needed on the file system of TargetDir, in bytes
+	RequiredSpace uint64
+}
+
+// Run runs all checks. ctx bounds the PHP binary.
+func Run(ctx context.Context, opts Options) []Result {
+	results := checkPHP(ctx, opts)
+	results = append(results, checkDiskSpace(opts.TargetDir, opts.RequiredSpace))
+	results = append(results, checkWritable(opts.TargetDir))
+	return results
+}
+
+// CoreRequirements returns the PHP requirements of a MediaWiki release from its composer.json,
+// or from a built-in table of minimum PHP versions if composerJSON is nil
+func CoreRequirements(mwVersion string, composerJSON []byte) (*manifest.Manifest, error) {
+	name := "MediaWiki " + mwVersion
+	if composerJSON != nil {
+		return manifest.ParseComposer(name, composerJSON)
+	}
+
+	minimum, found := mediawiki.MinimumPHP(mwVersion)
+	if !found {
+		return nil, fmt.Errorf("PHP requirements of MediaWiki %s are not known", mwVersion)
+	}
+	return &manifest.Manifest{Name: name, Requires: manifest.Requires{Platform: map[string]string{"php": ">= " + minimum}}}, nil
+}
+
+// Failed reports whether any result failed
+func Failed(results []Result) bool {
+	for _, result := range results {
+		if result.Status == StatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes the results, one per line
+func Print(w io.Writer, results []Result) {
+	labels := map[string]string{StatusOK: " OK ", StatusWarning: "WARN", StatusFailed: "FAIL"}
+	for _, result := range results {
+		fmt.Fprintf(w, "  [%s] %s\n", labels[result.Status], result.Message)
+	}
+}
+
+// checkPHP runs the PHP binary and checks its version and extensions against the requirements
+func checkPHP(ctx context.Context, opts Options) []Result {
+	info := opts.PHP
+	if info == nil {
+		var err error
+		if info, err = php.Detect(ctx, opts.PHPBinary); err != nil {
+			// Many hosts serve PHP through FPM or a web server module without a CLI on the PATH, so
+			// only a binary the user asked for has to be there
+			status := StatusWarning
+			if opts.PHPRequired {
+				status = StatusFailed
+			}
+			return []Result{{status, fmt.Sprintf("PHP requirements cannot be checked: %v", err)}}
+		}
+	}
+
+	results := []Result{{StatusOK, fmt.Sprintf("PHP %s (%s) with %d extensions", info.Version, info.Binary, len(info.Extensions))}}
+
+	env := manifest.Environment{MediaWiki: opts.MediaWiki, PHP: info.Version, PHPExtensions: info.Extensions}
+	for _, requirement := range opts.Requirements {
+		problems := requirement.Check(env)
+		for _, problem := range problems {
+			results = append(results, Result{StatusFailed, fmt.Sprintf("%s %s", requirement.Name, problem)})
+		}
+		if len(problems) == 0 {
+			results = append(results, Result{StatusOK, fmt.Sprintf("PHP requirements of %s are met", requirement.Name)})
+		}
+	}
+
+	return results
+}
+
+// checkDiskSpace checks that the file system of dir has at least required bytes available
+func checkDiskSpace(dir string, required uint64) Result {
+	available, err := freeSpace(existingParent(dir))
+	if err != nil {
+		return Result{StatusWarning, fmt.Sprintf("Free disk space on %s is not checked: %v", dir, err)}
+	}
+
+	message := fmt.Sprintf("%s free on %s (%s needed)", formatBytes(available), dir, formatBytes(required))
+	if available < required {
+		return Result{StatusFailed, "Only " + message}
+	}
+	return Result{StatusOK, message}
+}
+
+// checkWritable checks that files can be created in dir and in the directories the update replaces
+func checkWritable(dir string) Result {
+	for _, subdir := range []string{"", "extensions", "skins", "vendor"} {
+		path := filepath.Join(dir, subdir)
+		if subdir != "" {
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+		}
+
+		probe, err := os.CreateTemp(existingParent(path), ".mediawiki-updater-probe-")
+		if err != nil {
+			return Result{StatusFailed, fmt.Sprintf("%s is not writable: %v", path, err)}
+		}
+		probe.Close()
+		os.Remove(probe.Name())
+	}
+
+	return Result{StatusOK, fmt.Sprintf("%s is writable", dir)}
+}
+
+// existingParent returns dir, or its closest parent that exists if the target has not been created yet
+func existingParent(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. 1.5 GiB
+func formatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	value, exponent := float64(bytes)/unit, 0
+	for value >= unit && exponent < 4 {
+		value /= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[exponent])
+}
+
+// DirSize returns the total size of the regular files below dir
+func DirSize(dir string) (uint64, error) {
+	var size uint64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += uint64(info.Size())
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,105 @@
+package doctor
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/SKevo18/mediawiki-updater/internal/manifest"
+	"github.com/SKevo18/mediawiki-updater/internal/php"
+)
+
+func TestRunPHPRequirements(t *testing.T) {
+	core, err := CoreRequirements("1.43.1", []byte(`{"require": {"php": ">=8.1.0", "ext-intl": "*", "wikimedia/cdb": "3.0.0"}}`))
+	if err != nil {
+		t.Fatalf("Failed to parse composer.json: %v", err)
+	}
+
+	opts := Options{
+		TargetDir:     t.TempDir(),
+		PHP:           &php.Info{Binary: "/usr/bin/php", Version: "7.4.33", Extensions: []string{"Core", "mbstring"}},
+		MediaWiki:     "1.43.1",
+		Requirements:  []*manifest.Manifest{core},
+		RequiredSpace: 1,
+	}
+
+	results := Run(t.Context(), opts)
+	if !Failed(results) {
+		t.Fatalf("Expected PHP 7.4 to fail the requirements of MediaWiki 1.43, got %+v", results)
+	}
+
+	var failures []string
+	for _, result := range results {
+		if result.Status == StatusFailed {
+			failures = append(failures, result.Message)
+		}
+	}
+	if len(failures) != 2 || !strings.Contains(strings.Join(failures, "\n"), "found 7.4.33") {
+		t.Errorf("Unexpected failures: %q", failures)
+	}
+
+	opts.PHP = &php.Info{Binary: "/usr/bin/php", Version: "8.2.7", Extensions: []string{"Core", "intl"}}
+	if results := Run(t.Context(), opts); Failed(results) {
+		t.Errorf("Expected PHP 8.2 with intl to pass, got %+v", results)
+	}
+}
+
+func TestRunMissingPHP(t *testing.T) {
+	opts := Options{TargetDir: t.TempDir(), PHPBinary: filepath.Join(t.TempDir(), "php")}
+	if results := Run(t.Context(), opts); Failed(results) || results[0].Status != StatusWarning {
+		t.Errorf("Expected a missing PHP binary from the PATH to warn, got %+v", results)
+	}
+
+	opts.PHPRequired = true
+	if results := Run(t.Context(), opts); !Failed(results) || results[0].Status != StatusFailed {
+		t.Errorf("Expected a missing configured PHP binary to fail, got %+v", results)
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	dir := t.TempDir()
+	if result := checkWritable(filepath.Join(dir, "not", "created", "yet")); result.Status != StatusOK {
+		t.Errorf("Expected a missing target below a writable directory to pass, got %+v", result)
+	}
+
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("directory permissions are not enforced")
+	}
+	readOnly := filepath.Join(dir, "extensions")
+	if err := os.Mkdir(readOnly, 0o555); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if result := checkWritable(dir); result.Status != StatusFailed {
+		t.Errorf("Expected a read-only extensions directory to fail, got %+v", result)
+	}
+}
+
+func TestCoreRequirementsFallback(t *testing.T) {
+	core, err := CoreRequirements("1.39.10", nil)
+	if err != nil {
+		t.Fatalf("Failed to get built-in requirements: %v", err)
+	}
+	if constraint := core.Requires.Platform["php"]; constraint != ">= 7.4.3" {
+		t.Errorf("Expected >= 7.4.3, got %q", constraint)
+	}
+
+	if _, err := CoreRequirements("1.20.0", nil); err == nil {
+		t.Error("Expected error for an unknown MediaWiki version")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[uint64]string{
+		512:                  "512 B",
+		1536:                 "1.5 KiB",
+		DefaultRequiredSpace: "512.0 MiB",
+		3 << 30:              "3.0 GiB",
+	}
+	for bytes, expected := range tests {
+		if actual := formatBytes(bytes); actual != expected {
+			t.Errorf("formatBytes(%d) = %q, expected %q", bytes, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package downloader
+
+import (
+	"context"
+	"slices"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+)
+
+// Distributor fetches extensions and skins from a source other than the built-in extdist and
+// git distributors, for components whose distributor attribute names it
+type Distributor interface {
+	// Download installs the component into dir, the directory named after the component. The
+	// version tag is the REL branch of the MediaWiki version, like "REL1_43".
+	Download(ctx context.Context, component config.ComponentConfig, dir, versionTag string) (*Result, error)
+	// Resolve checks that the component can be downloaded without downloading it
+	Resolve(ctx context.Context, component config.ComponentConfig, versionTag string) (*Result, error)
+}
+
+// builtinDistributors are the distributors implemented by the Downloader itself
+var builtinDistributors = []string{"extdist", "git"}
+
+// IsBuiltin reports whether name is a distributor implemented by the Downloader itself
+func IsBuiltin(name string) bool {
+	return slices.Contains(builtinDistributors, name)
+}
+
+// HasDistributor reports whether name is a built-in distributor or one of the Distributors of
+// the options
+func (d *Downloader) HasDistributor(name string) bool {
+	_, ok := d.distributors[name]
+	return ok || IsBuiltin(name)
+}
@@ -1,197 +1,407 @@
 package downloader
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"maps"
+	"net/url"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/extdist"
 	"github.com/SKevo18/mediawiki-updater/internal/extractor"
 	"github.com/SKevo18/mediawiki-updater/internal/httputil"
-)
-
-const (
-	ExtDistURL = "https://extdist.wmflabs.org/dist/extensions/"
-	SkinsURL   = "https://extdist.wmflabs.org/dist/skins/"
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+	"github.com/SKevo18/mediawiki-updater/internal/progress"
 )
 
 // Downloader handles downloading files from various sources
 type Downloader struct {
-	extractor *extractor.Extractor
+	extractor    *extractor.Extractor
+	extDist      *extdist.Client
+	git          gitBackend
+	progress     progress.Reporter
+	http         *httputil.Client
+	logger       logging.Logger
+	distributors map[string]Distributor
+}
+
+// Options contains configuration options for the downloader
+type Options struct {
+	// UseGitBinary fetches Git components with the git binary instead of the built-in implementation
+	UseGitBinary bool
+	// ExtDistAPIs are the MediaWiki APIs used to look up ExtDist archives, see extdist.Options
+	ExtDistAPIs []string
+	// ExtDistMirrors are mirrors of the ExtDist archive directories, see extdist.Options
+	ExtDistMirrors []string
+	// Progress is notified of the progress of every file download; nil reports nothing
+	Progress progress.Reporter
+	// HTTPClient sends all requests, including Git over HTTP(S); nil uses a client with the
+	// default settings
+	HTTPClient *httputil.Client
+	// Logger receives the messages printed while downloading; nil prints them to standard output
+	Logger logging.Logger
+	// Distributors download the components whose distributor attribute names them. The names of
+	// the built-in distributors cannot be used.
+	Distributors map[string]Distributor
+}
+
+// Result describes a component that has been downloaded
+type Result struct {
+	Dir     string // directory the component was installed into
+	Version string // branch, tag or commit that was requested
+	Commit  string // resolved commit SHA, empty if unknown
+	URL     string // URL the component was fetched from
+	// Requested is the branch that was requested if a fallback branch was downloaded instead
+	Requested string
 }
 
-// NewDownloader creates a new Downloader instance
-func NewDownloader() *Downloader {
+// NewDownloader creates a new Downloader instance. It fails if a distributor of the options uses
+// the name of a built-in one.
+func NewDownloader(opts Options) (*Downloader, error) {
+	for name := range opts.Distributors {
+		if name == "" || IsBuiltin(name) {
+			return nil, fmt.Errorf("invalid distributor name %q", name)
+		}
+	}
+
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = httputil.DefaultClient()
+	}
+	logger := logging.OrStdout(opts.Logger)
+
+	var git gitBackend
+	if opts.UseGitBinary {
+		git = execGit{http: httpClient.Options(), logger: logger}
+	} else {
+		git = newGoGit(httpClient.HTTP(), logger)
+	}
+
+	reporter := opts.Progress
+	if reporter == nil {
+		reporter = progress.Nop
+	}
+
 	return &Downloader{
 		extractor: extractor.NewExtractor(),
-	}
+		extDist: extdist.NewClient(extdist.Options{
+			APIURLs:    opts.ExtDistAPIs,
+			IndexURLs:  opts.ExtDistMirrors,
+			HTTPClient: httpClient,
+			Logger:     logger,
+		}),
+		git:          git,
+		progress:     reporter,
+		http:         httpClient,
+		logger:       logger,
+		distributors: maps.Clone(opts.Distributors),
+	}, nil
+}
+
+// DownloadFile downloads a file from URL to the specified path, reporting its progress to the
+// Progress reporter of the options
+func (d *Downloader) DownloadFile(ctx context.Context, url, targetPath string) error {
+	_, err := d.downloadFirst(ctx, []string{url}, targetPath)
+	return err
 }
 
-// DownloadFile downloads a file from URL to the specified path
-func (d *Downloader) DownloadFile(url, targetPath string) error {
-	resp, err := httputil.Get(url)
+// downloadFirst downloads a file from the first of the URLs that serves it to the specified path,
+// and returns the URL it was downloaded from
+func (d *Downloader) downloadFirst(ctx context.Context, urls []string, targetPath string) (string, error) {
+	resp, url, err := d.http.GetFirst(ctx, urls)
 	if err != nil {
-		return fmt.Errorf("failed to download file: %w", err)
+		return "", fmt.Errorf("failed to download file: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed with status %d", resp.StatusCode)
-	}
-
 	file, err := os.Create(targetPath)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return "", fmt.Errorf("failed to create file: %w", err)
 	}
 	defer file.Close()
 
-	_, err = io.Copy(file, resp.Body)
+	tracker := d.progress.Start(fileName(url), resp.ContentLength)
+	_, err = io.Copy(file, progress.Reader(resp.Body, tracker))
+	tracker.Done(err)
 	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+		return "", fmt.Errorf("failed to write file: %w", err)
 	}
 
-	return nil
+	return url, nil
 }
 
-// DownloadAndExtract downloads a file and extracts it to the target directory
-func (d *Downloader) DownloadAndExtract(url, targetDir string, isMediaWiki bool) error {
+// DownloadAndExtract downloads a file and extracts it to the target directory.
+// If stripTopDir is set, the top-level directory of the archive is removed.
+func (d *Downloader) DownloadAndExtract(ctx context.Context, url, targetDir string, stripTopDir bool) error {
+	_, err := d.downloadAndExtract(ctx, []string{url}, targetDir, stripTopDir, "")
+	return err
+}
+
+// downloadAndExtract downloads a file from the first of the URLs that serves it, verifies its
+// SHA-256 checksum if one is given, and extracts it to the target directory.
+// It returns the URL the file was downloaded from.
+func (d *Downloader) downloadAndExtract(ctx context.Context, urls []string, targetDir string, stripTopDir bool, checksum string) (string, error) {
 	tempFile, err := os.CreateTemp("", "mw-temp-*.tar.gz")
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+		return "", fmt.Errorf("failed to create temp file: %w", err)
 	}
 	defer os.Remove(tempFile.Name())
 	defer tempFile.Close()
 
-	if err := d.DownloadFile(url, tempFile.Name()); err != nil {
-		return err
+	url, err := d.downloadFirst(ctx, urls, tempFile.Name())
+	if err != nil {
+		return "", err
 	}
 
 	// Reopen for reading
 	tempFile.Close()
+	if checksum != "" {
+		if err := verifyChecksum(tempFile.Name(), checksum); err != nil {
+			return "", err
+		}
+	}
+
 	file, err := os.Open(tempFile.Name())
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer file.Close()
 
-	if isMediaWiki {
-		return d.extractor.ExtractMediaWikiCore(file, targetDir)
+	if stripTopDir {
+		err = d.extractor.ExtractStripped(ctx, file, targetDir)
+	} else {
+		err = d.extractor.ExtractArchive(ctx, file, targetDir, nil)
 	}
-	return d.extractor.ExtractArchive(file, targetDir, nil)
+	return url, err
 }
 
 // DownloadComponent downloads a component (extension or skin) based on its configuration
-func (d *Downloader) DownloadComponent(component config.ComponentConfig, targetDir, versionTag string) error {
+func (d *Downloader) DownloadComponent(ctx context.Context, component config.ComponentConfig, targetDir, versionTag string) (*Result, error) {
+	var result *Result
+	var err error
+
 	switch component.Distributor {
 	case "extdist":
-		return d.downloadFromExtDist(component, targetDir, versionTag)
+		result, err = d.downloadFromExtDist(ctx, component, targetDir, versionTag)
 	case "git":
-		return d.downloadFromGit(component, targetDir)
+		result, err = d.downloadFromGit(ctx, component, targetDir)
 	default:
-		return fmt.Errorf("unknown distributor: %s", component.Distributor)
+		distributor, ok := d.distributors[component.Distributor]
+		if !ok {
+			return nil, fmt.Errorf("unknown distributor: %s", component.Distributor)
+		}
+		dir := filepath.Join(targetDir, component.DirName())
+		result, err = distributor.Download(ctx, component, dir, versionTag)
+		if result != nil && result.Dir == "" {
+			result.Dir = dir
+		}
+	}
+	if err != nil {
+		return nil, err
 	}
+
+	if err := d.extractor.RemovePaths(result.Dir, component.Exclude); err != nil {
+		return nil, fmt.Errorf("failed to remove excluded paths: %w", err)
+	}
+
+	return result, nil
 }
 
-// downloadFromExtDist downloads a component from the ExtDist service
-func (d *Downloader) downloadFromExtDist(component config.ComponentConfig, targetDir, versionTag string) error {
-	// Determine base URL based on target directory
-	baseURL := ExtDistURL
-	if strings.Contains(targetDir, "skins") {
-		baseURL = SkinsURL
+// Resolve checks that a component can be downloaded without downloading it.
+// The result contains the URL of the ExtDist archive or the resolved Git commit.
+func (d *Downloader) Resolve(ctx context.Context, component config.ComponentConfig, versionTag string) (*Result, error) {
+	switch component.Distributor {
+	case "extdist":
+		version := versionTag
+		if component.Version != "" {
+			version = component.Version
+		}
+
+		archive, err := d.lookupExtDist(ctx, component, version)
+		if err != nil {
+			return nil, err
+		}
+		return extDistResult("", version, archive), nil
+
+	case "git":
+		return d.resolveGit(ctx, component)
+
+	default:
+		distributor, ok := d.distributors[component.Distributor]
+		if !ok {
+			return nil, fmt.Errorf("unknown distributor: %s", component.Distributor)
+		}
+		return distributor.Resolve(ctx, component, versionTag)
 	}
+}
 
+// downloadFromExtDist downloads a component from the ExtDist service
+func (d *Downloader) downloadFromExtDist(ctx context.Context, component config.ComponentConfig, targetDir, versionTag string) (*Result, error) {
 	// Use component version if specified, otherwise use the global version tag
 	version := versionTag
 	if component.Version != "" {
 		version = component.Version
 	}
 
-	downloadURL, err := d.getExtDistDownloadURL(baseURL, component.Name, version)
+	archive, err := d.lookupExtDist(ctx, component, version)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if downloadURL == "" {
-		return fmt.Errorf("component not found: %s", component.Name)
+	componentDir := filepath.Join(targetDir, component.DirName())
+
+	var url string
+	err = FetchInto(componentDir, func(dir string) error {
+		url, err = d.downloadAndExtract(ctx, d.extDist.URLs(archive), dir, true, component.SHA256)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return d.DownloadAndExtract(downloadURL, targetDir, false)
+	result := extDistResult(componentDir, version, archive)
+	result.URL = url
+	return result, nil
 }
 
-// downloadFromGit clones a Git repository
-func (d *Downloader) downloadFromGit(component config.ComponentConfig, targetDir string) error {
-	// component.Name should be the git repository URL for git distributor
-	repoURL := component.Name
-	version := component.Version
-	if version == "" {
-		version = "master"
+// FetchInto runs fetch on an empty temporary directory next to dir and replaces dir with it if
+// fetch succeeds, so that a failed download leaves no partial component behind
+func FetchInto(dir string, fetch func(dir string) error) error {
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return fmt.Errorf("failed to create component directory: %w", err)
 	}
 
-	// Create target directory for this component
-	componentDir := filepath.Join(targetDir, extractRepoName(repoURL))
+	tempDir, err := os.MkdirTemp(parent, "."+filepath.Base(dir)+".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create component directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
 
-	// Clone the repository
-	cmd := exec.Command("git", "clone", "--branch", version, "--depth", "1", repoURL, componentDir)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to clone git repository %s: %w", repoURL, err)
+	// MkdirTemp creates the directory accessible to its owner only
+	if err := os.Chmod(tempDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create component directory: %w", err)
 	}
 
-	// Remove .git directory to clean up
-	gitDir := filepath.Join(componentDir, ".git")
-	if err := os.RemoveAll(gitDir); err != nil {
-		return fmt.Errorf("failed to remove .git directory: %w", err)
+	if err := fetch(tempDir); err != nil {
+		return err
 	}
 
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to replace component directory: %w", err)
+	}
+	if err := os.Rename(tempDir, dir); err != nil {
+		return fmt.Errorf("failed to replace component directory: %w", err)
+	}
 	return nil
 }
 
-// getExtDistDownloadURL finds the download URL for a component from ExtDist
-func (d *Downloader) getExtDistDownloadURL(baseURL, componentName, version string) (string, error) {
-	fmt.Printf("    Checking %s for %s-%s\n", baseURL, componentName, version)
-
-	resp, err := httputil.Get(baseURL)
-	if err != nil {
-		return "", err
+// extDistResult describes a downloaded or resolved ExtDist archive of the requested branch
+func extDistResult(dir, requested string, archive *extdist.Archive) *Result {
+	result := &Result{
+		Dir:     dir,
+		Version: archive.Branch,
+		Commit:  archive.Hash,
+		URL:     archive.URL,
 	}
-	defer resp.Body.Close()
+	if archive.Branch != requested {
+		result.Requested = requested
+	}
+	return result
+}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+// lookupExtDist finds the ExtDist archive of a component branch. If there is no snapshot for
+// the branch, the fallback branches of the component are tried in order.
+func (d *Downloader) lookupExtDist(ctx context.Context, component config.ComponentConfig, version string) (*extdist.Archive, error) {
+	d.logger.Printf("    Looking up %s-%s on ExtDist\n", component.Name, version)
+
+	archive, err := d.extDist.Archive(ctx, extDistType(component), component.Name, version)
+	for _, fallback := range component.Fallback {
+		if !errors.Is(err, extdist.ErrNotFound) {
+			break
+		}
+		if fallback == version || fallback == config.NoFallback {
+			continue
+		}
+
+		fallbackArchive, fallbackErr := d.extDist.Archive(ctx, extDistType(component), component.Name, fallback)
+		if fallbackErr == nil {
+			d.logger.Printf("    WARNING: ExtDist has no %s snapshot of %s, FALLING BACK TO %s\n", version, component.Name, fallback)
+			archive, err = fallbackArchive, nil
+		} else if !errors.Is(fallbackErr, extdist.ErrNotFound) {
+			// Only a missing snapshot moves on to the next branch; the primary error is kept as text
+			// so that the failure is not reported as not found
+			return nil, fmt.Errorf("%v; failed to look up fallback %s: %w", err, fallback, fallbackErr)
+		}
+	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	var downloadURL string
-	searchPattern := fmt.Sprintf("%s-%s", componentName, version)
+	d.logger.Printf("    Found: %s\n", archive.URL)
+	return archive, nil
+}
 
-	doc.Find("a").Each(func(i int, s *goquery.Selection) {
-		href := s.AttrOr("href", "")
-		if strings.Contains(href, searchPattern) && strings.HasSuffix(href, ".tar.gz") {
-			downloadURL = href
+// PrefetchExtDist looks up the ExtDist archives of all given components in as few requests as
+// possible, so that downloading them one by one does not query ExtDist again
+func (d *Downloader) PrefetchExtDist(ctx context.Context, components []config.ComponentConfig) error {
+	names := make(map[string][]string)
+	for _, component := range components {
+		if component.Distributor == "extdist" {
+			componentType := extDistType(component)
+			names[componentType] = append(names[componentType], component.Name)
 		}
-	})
+	}
 
-	if downloadURL == "" {
-		fmt.Printf("    No match found for pattern: %s\n", searchPattern)
-		return "", nil
+	for componentType, typeNames := range names {
+		if _, err := d.extDist.Branches(ctx, componentType, typeNames...); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	fullURL := baseURL + downloadURL
-	fmt.Printf("    Found: %s\n", fullURL)
-	return fullURL, nil
+// extDistType returns the ExtDist repository type of the component, defaulting to extensions
+func extDistType(component config.ComponentConfig) string {
+	if component.Type == config.TypeSkin {
+		return config.TypeSkin
+	}
+	return config.TypeExtension
+}
+
+// verifyChecksum checks that the SHA-256 checksum of a file matches the expected hex digest
+func verifyChecksum(path, expected string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("failed to compute checksum: %w", err)
+	}
+
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch: expected sha256 %s, got %s", expected, actual)
+	}
+
+	return nil
 }
 
-// extractRepoName extracts repository name from a Git URL
-func extractRepoName(repoURL string) string {
-	// Remove .git suffix and extract last part of URL
-	repoURL = strings.TrimSuffix(repoURL, ".git")
-	parts := strings.Split(repoURL, "/")
-	if len(parts) > 0 {
-		return parts[len(parts)-1]
+// fileName returns the last element of the path of a URL or local file, used to name downloads
+func fileName(rawURL string) string {
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Path != "" {
+		rawURL = parsed.Path
 	}
-	return "unknown"
+	return path.Base(filepath.ToSlash(rawURL))
 }
@@ -0,0 +1,94 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/extdist"
+	"github.com/SKevo18/mediawiki-updater/internal/progress"
+)
+
+// newTestDownloader creates a Downloader for a test
+func newTestDownloader(t *testing.T, opts Options) *Downloader {
+	t.Helper()
+	d, err := NewDownloader(opts)
+	if err != nil {
+		t.Fatalf("Failed to create downloader: %v", err)
+	}
+	return d
+}
+
+func TestResolveExtDistFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"query": {"extdistbranches": {"extensions": {"Example": {
+			"REL1_41": "https://extdist.example/dist/extensions/Example-REL1_41-1111111.tar.gz",
+			"master": "https://extdist.example/dist/extensions/Example-master-2222222.tar.gz"
+		}}}}}`)
+	}))
+	defer server.Close()
+
+	d := newTestDownloader(t, Options{ExtDistAPIs: []string{server.URL}})
+	component := config.ComponentConfig{Type: config.TypeExtension, Distributor: "extdist", Name: "Example"}
+
+	if _, err := d.Resolve(t.Context(), component, "REL1_43"); !errors.Is(err, extdist.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound without a fallback, got %v", err)
+	}
+
+	component.Fallback = []string{"REL1_42", "master"}
+	result, err := d.Resolve(t.Context(), component, "REL1_43")
+	if err != nil {
+		t.Fatalf("Expected the fallback to resolve, got %v", err)
+	}
+	if result.Version != "master" || result.Requested != "REL1_43" || result.Commit != "2222222" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	component.Version = "REL1_41"
+	if result, err := d.Resolve(t.Context(), component, "REL1_43"); err != nil || result.Requested != "" {
+		t.Errorf("Expected an existing branch not to fall back, got %+v, %v", result, err)
+	}
+}
+
+// reporter records the downloads reported to it
+type reporter struct {
+	name string
+	size int64
+	done int64
+	err  error
+	over bool
+}
+
+func (r *reporter) Start(name string, size int64) progress.Tracker {
+	r.name, r.size = name, size
+	return r
+}
+
+func (r *reporter) Progress(done int64) { r.done = done }
+
+func (r *reporter) Done(err error) { r.err, r.over = err, true }
+
+func TestDownloadFileProgress(t *testing.T) {
+	body := strings.Repeat("x", 4096)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	r := &reporter{}
+	d := newTestDownloader(t, Options{Progress: r})
+	target := filepath.Join(t.TempDir(), "core.tar.gz")
+	if err := d.DownloadFile(t.Context(), server.URL+"/1.43/mediawiki-1.43.1.tar.gz?mirror=1", target); err != nil {
+		t.Fatalf("Failed to download: %v", err)
+	}
+
+	if r.name != "mediawiki-1.43.1.tar.gz" || r.size != int64(len(body)) || r.done != int64(len(body)) || !r.over || r.err != nil {
+		t.Errorf("Unexpected progress: %+v", r)
+	}
+}
@@ -0,0 +1,111 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+)
+
+// commitSHAPattern matches a full 40-character hexadecimal commit SHA
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+// gitBackend fetches Git repositories
+type gitBackend interface {
+	// resolveRef resolves a branch or tag to a commit SHA
+	resolveRef(ctx context.Context, repoURL, ref string, creds *gitCredentials) (string, error)
+	// checkout fetches a single commit into dir, optionally with its submodules
+	checkout(ctx context.Context, repoURL, commit, dir string, submodules bool, creds *gitCredentials) error
+}
+
+// downloadFromGit fetches a Git repository at the configured branch, tag or commit
+func (d *Downloader) downloadFromGit(ctx context.Context, component config.ComponentConfig, targetDir string) (*Result, error) {
+	// component.Name should be the git repository URL for git distributor
+	repoURL := component.Name
+
+	result, err := d.resolveGit(ctx, component)
+	if err != nil {
+		return nil, err
+	}
+	d.logger.Printf("    Resolved %s to commit %s\n", result.Version, result.Commit)
+
+	creds, err := resolveGitCredentials(component.Auth, repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	componentDir := filepath.Join(targetDir, component.DirName())
+	err = FetchInto(componentDir, func(dir string) error {
+		if err := d.git.checkout(ctx, repoURL, result.Commit, dir, component.Submodules, creds); err != nil {
+			return err
+		}
+
+		// Remove Git metadata (including submodule .git files) to clean up
+		if err := removeGitMetadata(dir); err != nil {
+			return fmt.Errorf("failed to remove .git directory: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Dir = componentDir
+	return result, nil
+}
+
+// resolveGit resolves the configured branch, tag or commit of a Git component to a commit SHA
+func (d *Downloader) resolveGit(ctx context.Context, component config.ComponentConfig) (*Result, error) {
+	version := component.Version
+	if version == "" {
+		version = "master"
+	}
+
+	if commitSHAPattern.MatchString(version) {
+		return &Result{Version: version, Commit: strings.ToLower(version), URL: component.Name}, nil
+	}
+
+	creds, err := resolveGitCredentials(component.Auth, component.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := d.git.resolveRef(ctx, component.Name, version, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Version: version, Commit: commit, URL: component.Name}, nil
+}
+
+// removeGitMetadata removes all .git directories and files below dir
+func removeGitMetadata(dir string) error {
+	var gitPaths []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Name() == ".git" {
+			gitPaths = append(gitPaths, path)
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, path := range gitPaths {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
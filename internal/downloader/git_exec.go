@@ -0,0 +1,222 @@
+package downloader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/SKevo18/mediawiki-updater/internal/httputil"
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+)
+
+// execGit is a gitBackend that runs the git binary
+type execGit struct {
+	http   httputil.ClientOptions
+	logger logging.Logger
+}
+
+// resolveRef resolves a branch or tag to a commit SHA using git ls-remote
+func (g execGit) resolveRef(ctx context.Context, repoURL, ref string, creds *gitCredentials) (string, error) {
+	output, err := g.run(ctx, "", creds, "ls-remote", repoURL,
+		"refs/heads/"+ref, "refs/tags/"+ref, "refs/tags/"+ref+"^{}")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s in %s: %w", ref, repoURL, wrapGitError(repoURL, err))
+	}
+
+	// Prefer the peeled commit of an annotated tag, then the tag itself, then the branch
+	refs := parseLsRemote(output)
+	for _, name := range []string{"refs/tags/" + ref + "^{}", "refs/tags/" + ref, "refs/heads/" + ref} {
+		if sha, ok := refs[name]; ok {
+			return sha, nil
+		}
+	}
+
+	return "", fmt.Errorf("no branch or tag named %s in %s", ref, repoURL)
+}
+
+// checkout fetches a single commit into dir and checks it out
+func (g execGit) checkout(ctx context.Context, repoURL, commit, dir string, submodules bool, creds *gitCredentials) error {
+	if _, err := g.run(ctx, dir, creds, "init", "--quiet"); err != nil {
+		return fmt.Errorf("failed to initialize git repository: %w", err)
+	}
+
+	// Submodules with relative URLs are resolved against the origin remote
+	if _, err := g.run(ctx, dir, creds, "remote", "add", "origin", repoURL); err != nil {
+		return fmt.Errorf("failed to configure git remote: %w", err)
+	}
+
+	// Shallow fetch by SHA works on servers that allow it (GitHub, Gerrit, GitLab);
+	// otherwise fall back to fetching all branches and tags
+	if _, err := g.run(ctx, dir, creds, "fetch", "--quiet", "--depth", "1", "origin", commit); err != nil {
+		if wrapped := wrapGitError(repoURL, err); wrapped != err {
+			return wrapped
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		g.logger.Printf("    Shallow fetch of %s failed, fetching full history\n", commit)
+		if _, err := g.run(ctx, dir, creds, "fetch", "--quiet", "--tags", "origin", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+			return fmt.Errorf("failed to fetch git repository %s: %w", repoURL, wrapGitError(repoURL, err))
+		}
+	}
+
+	if _, err := g.run(ctx, dir, creds, "-c", "advice.detachedHead=false", "checkout", "--quiet", commit); err != nil {
+		return fmt.Errorf("failed to check out commit %s: %w", commit, err)
+	}
+
+	if submodules {
+		if _, err := g.run(ctx, dir, creds, "submodule", "update", "--quiet", "--init", "--recursive", "--depth", "1"); err != nil {
+			return fmt.Errorf("failed to fetch submodules of %s: %w", repoURL, wrapGitError(repoURL, err))
+		}
+	}
+
+	return nil
+}
+
+// run runs a git command in dir that never prompts and passes the given credentials and the HTTP
+// client settings. The command is killed when ctx is cancelled. It returns the standard output;
+// errors include the trimmed standard error of the command.
+func (g execGit) run(ctx context.Context, dir string, creds *gitCredentials, args ...string) (string, error) {
+	env, cleanup, err := httpEnv(g.http)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	if g.http.UserAgentSuffix != "" {
+		args = append([]string{"-c", "http.userAgent=" + g.http.UserAgent()}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Env = append(cmd.Env, creds.env(cmd.Env)...)
+	cmd.Env = append(cmd.Env, env...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+
+	return stdout.String(), nil
+}
+
+// httpEnv returns the environment variables that pass the HTTP client settings to the git binary,
+// and a function that removes the certificate bundle it writes for extra CA files
+func httpEnv(opts httputil.ClientOptions) ([]string, func(), error) {
+	var env []string
+	if opts.Proxy != "" {
+		env = append(env, "http_proxy="+opts.Proxy, "https_proxy="+opts.Proxy)
+	}
+	if len(opts.NoProxy) > 0 {
+		env = append(env, "no_proxy="+strings.Join(opts.NoProxy, ","))
+	}
+	if opts.ClientCert != "" {
+		env = append(env, "GIT_SSL_CERT="+opts.ClientCert, "GIT_SSL_KEY="+opts.ClientKey)
+	}
+	if len(opts.CAFiles) == 0 {
+		return env, func() {}, nil
+	}
+
+	bundle, err := caBundle(opts.CAFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	env = append(env, "GIT_SSL_CAINFO="+bundle)
+	return env, func() { os.Remove(bundle) }, nil
+}
+
+// systemCABundles are the certificate bundles of common distributions, in the order Go looks for them
+var systemCABundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+}
+
+// caBundle writes the system certificate authorities followed by those in files to a temporary
+// file. Git replaces its certificate authorities with GIT_SSL_CAINFO instead of adding to them, so
+// the system ones have to be included.
+func caBundle(files []string) (string, error) {
+	var system []byte
+	for _, path := range append([]string{os.Getenv("GIT_SSL_CAINFO"), os.Getenv("SSL_CERT_FILE")}, systemCABundles...) {
+		if path == "" {
+			continue
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			system = data
+			break
+		}
+	}
+	if system == nil {
+		return "", fmt.Errorf("no system certificate bundle found to add the CA files to for the git binary; set SSL_CERT_FILE to one")
+	}
+
+	f, err := os.CreateTemp("", "mediawiki-updater-ca-*.pem")
+	if err != nil {
+		return "", fmt.Errorf("failed to create certificate bundle: %w", err)
+	}
+
+	err = writeCABundle(f, system, files)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// writeCABundle writes system and the contents of files to w, each ending with a newline
+func writeCABundle(w io.Writer, system []byte, files []string) error {
+	parts := [][]byte{system}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %w", err)
+		}
+		parts = append(parts, data)
+	}
+
+	for _, part := range parts {
+		if len(part) > 0 && part[len(part)-1] != '\n' {
+			part = append(part, '\n')
+		}
+		if _, err := w.Write(part); err != nil {
+			return fmt.Errorf("failed to write certificate bundle: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseLsRemote parses git ls-remote output into a map of ref name to commit SHA
+func parseLsRemote(output string) map[string]string {
+	refs := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			refs[fields[1]] = fields[0]
+		}
+	}
+	return refs
+}
@@ -0,0 +1,304 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitclient "github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// goGit is a gitBackend implemented in pure Go, so no git binary is required
+type goGit struct {
+	// transport fetches over HTTP(S) with the client of the Downloader, so its proxy, certificate
+	// authorities, client certificate and User-Agent settings apply to Git as well
+	transport transport.Transport
+	logger    logging.Logger
+}
+
+// newGoGit returns a goGit that fetches over HTTP(S) with client
+func newGoGit(client *http.Client, logger logging.Logger) goGit {
+	installClientTransport()
+	return goGit{transport: githttp.NewClient(client), logger: logger}
+}
+
+// installClientTransport replaces the go-git transports of http and https, which are global, once
+// with clientTransport. go-git only looks transports up by scheme, so the transport of a
+// Downloader is passed along with the credentials instead, and Downloaders with different HTTP
+// clients do not affect each other. Other users of go-git in the process keep the transports
+// installed before; a transport installed later replaces this one and breaks every Downloader.
+var installClientTransport = sync.OnceFunc(func() {
+	for _, scheme := range []string{"https", "http"} {
+		fallback := gitclient.Protocols[scheme]
+		if fallback == nil {
+			fallback = githttp.DefaultClient
+		}
+		gitclient.InstallProtocol(scheme, clientTransport{fallback: fallback})
+	}
+})
+
+// clientAuth carries the HTTP transport of a Downloader to clientTransport, along with the
+// credentials of the component, if any
+type clientAuth struct {
+	transport transport.Transport
+	auth      githttp.AuthMethod // nil without credentials
+}
+
+func (a *clientAuth) Name() string {
+	if a.auth == nil {
+		return "client"
+	}
+	return a.auth.Name()
+}
+
+func (a *clientAuth) String() string {
+	if a.auth == nil {
+		return "client"
+	}
+	return a.auth.String()
+}
+
+// clientTransport opens HTTP(S) sessions with the transport of a clientAuth, and with the
+// transport it replaced for any other authentication
+type clientTransport struct {
+	fallback transport.Transport
+}
+
+func (t clientTransport) NewUploadPackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.UploadPackSession, error) {
+	session, auth := t.session(auth)
+	return session.NewUploadPackSession(ep, auth)
+}
+
+func (t clientTransport) NewReceivePackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.ReceivePackSession, error) {
+	session, auth := t.session(auth)
+	return session.NewReceivePackSession(ep, auth)
+}
+
+// session returns the transport and the credentials to open a session with
+func (t clientTransport) session(auth transport.AuthMethod) (transport.Transport, transport.AuthMethod) {
+	a, ok := auth.(*clientAuth)
+	if !ok {
+		return t.fallback, auth
+	}
+	if a.auth == nil {
+		// A nil AuthMethod in the interface would be taken for credentials
+		return a.transport, nil
+	}
+	return a.transport, a.auth
+}
+
+// auth returns the go-git authentication for repoURL: the credentials, and for HTTP(S) URLs the
+// transport of the Downloader
+func (g goGit) auth(repoURL string, creds *gitCredentials) (transport.AuthMethod, error) {
+	auth, err := creds.authMethod(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := transport.NewEndpoint(repoURL)
+	if err != nil || (endpoint.Protocol != "http" && endpoint.Protocol != "https") {
+		return auth, nil
+	}
+
+	httpAuth, ok := auth.(githttp.AuthMethod)
+	if auth != nil && !ok {
+		return nil, fmt.Errorf("ssh key authentication requires an ssh repository URL, got %s", repoURL)
+	}
+	return &clientAuth{transport: g.transport, auth: httpAuth}, nil
+}
+
+// resolveRef resolves a branch or tag to a commit SHA by listing the remote references
+func (g goGit) resolveRef(ctx context.Context, repoURL, ref string, creds *gitCredentials) (string, error) {
+	auth, err := g.auth(repoURL, creds)
+	if err != nil {
+		return "", err
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repoURL},
+	})
+
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth, PeelingOption: git.AppendPeeled})
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s in %s: %w", ref, repoURL, wrapGitError(repoURL, err))
+	}
+
+	hashes := make(map[string]string, len(refs))
+	for _, r := range refs {
+		if r.Type() == plumbing.HashReference {
+			hashes[r.Name().String()] = r.Hash().String()
+		}
+	}
+
+	// Prefer the peeled commit of an annotated tag, then the tag itself, then the branch
+	for _, name := range []string{"refs/tags/" + ref + "^{}", "refs/tags/" + ref, "refs/heads/" + ref} {
+		if sha, ok := hashes[name]; ok {
+			return sha, nil
+		}
+	}
+
+	return "", fmt.Errorf("no branch or tag named %s in %s", ref, repoURL)
+}
+
+// checkout fetches a single commit into dir and checks it out
+func (g goGit) checkout(ctx context.Context, repoURL, commit, dir string, submodules bool, creds *gitCredentials) error {
+	auth, err := g.auth(repoURL, creds)
+	if err != nil {
+		return err
+	}
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		return fmt.Errorf("failed to initialize git repository: %w", err)
+	}
+
+	// Submodules with relative URLs are resolved against the origin remote
+	remote, err := repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{repoURL}})
+	if err != nil {
+		return fmt.Errorf("failed to configure git remote: %w", err)
+	}
+
+	// Shallow fetch by SHA works on servers that allow it (GitHub, Gerrit, GitLab);
+	// otherwise fall back to fetching all branches and tags
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []gitconfig.RefSpec{gitconfig.RefSpec(commit + ":refs/heads/mediawiki-updater")},
+		Depth:    1,
+		Auth:     auth,
+		Tags:     git.NoTags,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		if wrapped := wrapGitError(repoURL, err); wrapped != err {
+			return wrapped
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		g.logger.Printf("    Shallow fetch of %s failed, fetching full history\n", commit)
+		err = remote.FetchContext(ctx, &git.FetchOptions{
+			RefSpecs: []gitconfig.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+			Auth:     auth,
+			Tags:     git.AllTags,
+		})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return fmt.Errorf("failed to fetch git repository %s: %w", repoURL, wrapGitError(repoURL, err))
+		}
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open git worktree: %w", err)
+	}
+
+	if err := worktree.Checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(commit), Force: true}); err != nil {
+		return fmt.Errorf("failed to check out commit %s: %w", commit, err)
+	}
+
+	if submodules {
+		return g.updateSubmodules(ctx, worktree, repoURL, repoURL, creds, int(git.DefaultSubmoduleRecursionDepth))
+	}
+
+	return nil
+}
+
+// updateSubmodules checks out the submodules of worktree, whose repository was fetched from
+// parentURL, down to depth levels. The credentials of credsURL are only sent to submodules on
+// the same host; the others are fetched without them.
+func (g goGit) updateSubmodules(ctx context.Context, worktree *git.Worktree, parentURL, credsURL string, creds *gitCredentials, depth int) error {
+	subs, err := worktree.Submodules()
+	if err != nil {
+		return fmt.Errorf("failed to read submodules of %s: %w", parentURL, err)
+	}
+
+	for _, sub := range subs {
+		subURL := submoduleURL(parentURL, sub.Config().URL)
+		subCreds := creds
+		if !sameHost(credsURL, subURL) {
+			subCreds = nil
+		}
+		auth, err := g.auth(subURL, subCreds)
+		if err != nil {
+			return err
+		}
+
+		err = sub.UpdateContext(ctx, &git.SubmoduleUpdateOptions{Init: true, Auth: auth, Depth: 1})
+		if err != nil {
+			return fmt.Errorf("failed to fetch submodule %s of %s: %w", sub.Config().Name, parentURL, wrapGitError(subURL, err))
+		}
+
+		if depth <= 1 {
+			continue
+		}
+		subRepo, err := sub.Repository()
+		if err != nil {
+			return fmt.Errorf("failed to open submodule %s: %w", sub.Config().Name, err)
+		}
+		subWorktree, err := subRepo.Worktree()
+		if err != nil {
+			return fmt.Errorf("failed to open submodule %s: %w", sub.Config().Name, err)
+		}
+		if err := g.updateSubmodules(ctx, subWorktree, subURL, credsURL, creds, depth-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// submoduleURL resolves the URL of a submodule, which may be relative to the URL of its parent
+// like ../other.git
+func submoduleURL(parentURL, url string) string {
+	if !strings.HasPrefix(url, "./") && !strings.HasPrefix(url, "../") {
+		return url
+	}
+	endpoint, err := transport.NewEndpoint(parentURL)
+	if err != nil {
+		return url
+	}
+	endpoint.Path = path.Join(endpoint.Path, url)
+	return endpoint.String()
+}
+
+// sameHost reports whether two repository URLs are on the same host. Local paths are only on the
+// same host as other local paths.
+func sameHost(a, b string) bool {
+	endpointA, errA := transport.NewEndpoint(a)
+	endpointB, errB := transport.NewEndpoint(b)
+	return errA == nil && errB == nil && endpointA.Host == endpointB.Host
+}
+
+// authMethod converts the credentials to a go-git transport.AuthMethod for repoURL. SSH keys are
+// used for the user of the URL, like deploy in ssh://deploy@host/repo.git, or git if it has none.
+func (c *gitCredentials) authMethod(repoURL string) (transport.AuthMethod, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	if c.SSHKeyPath != "" {
+		user := "git"
+		if endpoint, err := transport.NewEndpoint(repoURL); err == nil && endpoint.User != "" {
+			user = endpoint.User
+		}
+
+		auth, err := gitssh.NewPublicKeysFromFile(user, c.SSHKeyPath, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ssh key %s: %w", c.SSHKeyPath, err)
+		}
+		return auth, nil
+	}
+
+	return &githttp.BasicAuth{Username: c.Username, Password: c.Password}, nil
+}
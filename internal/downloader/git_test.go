@@ -0,0 +1,413 @@
+package downloader
+
+import (
+	"errors"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/httputil"
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// createTestRepo creates a local repository with two commits, a branch and an annotated tag
+func createTestRepo(t *testing.T) (repoDir string, first string, second string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+
+	repoDir = filepath.Join(t.TempDir(), "Example.git")
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repoDir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	if err := os.MkdirAll(repoDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	git("init", "--quiet", "--initial-branch=main")
+
+	os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte("first"), 0o644)
+	git("add", ".")
+	git("commit", "--quiet", "-m", "first")
+	first = git("rev-parse", "HEAD")
+	git("tag", "-a", "v1.0", "-m", "v1.0")
+
+	os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte("second"), 0o644)
+	git("commit", "--quiet", "-am", "second")
+	second = git("rev-parse", "HEAD")
+
+	return repoDir, first, second
+}
+
+func TestDownloadFromGit(t *testing.T) {
+	for name, opts := range map[string]Options{"go-git": {}, "binary": {UseGitBinary: true}} {
+		t.Run(name, func(t *testing.T) {
+			testDownloadFromGit(t, newTestDownloader(t, opts))
+		})
+	}
+}
+
+func testDownloadFromGit(t *testing.T, d *Downloader) {
+	repoDir, first, second := createTestRepo(t)
+
+	tests := []struct {
+		version  string
+		commit   string
+		contents string
+	}{
+		{"main", second, "second"},
+		{"v1.0", first, "first"},
+		{first, first, "first"},
+	}
+
+	for _, test := range tests {
+		targetDir := t.TempDir()
+		component := config.ComponentConfig{Distributor: "git", Name: repoDir, Version: test.version}
+
+		result, err := d.DownloadComponent(t.Context(), component, targetDir, "REL1_43")
+		if err != nil {
+			t.Fatalf("Unexpected error for version %s: %v", test.version, err)
+		}
+
+		if result.Commit != test.commit {
+			t.Errorf("Expected commit %s for version %s, got %s", test.commit, test.version, result.Commit)
+		}
+
+		data, err := os.ReadFile(filepath.Join(targetDir, "Example", "file.txt"))
+		if err != nil {
+			t.Fatalf("Failed to read checked out file: %v", err)
+		}
+		if string(data) != test.contents {
+			t.Errorf("Expected contents %q for version %s, got %q", test.contents, test.version, data)
+		}
+
+		if _, err := os.Stat(filepath.Join(targetDir, "Example", ".git")); !os.IsNotExist(err) {
+			t.Errorf("Expected .git directory to be removed for version %s", test.version)
+		}
+	}
+}
+
+func TestDownloadFromGitFailure(t *testing.T) {
+	repoDir, _, _ := createTestRepo(t)
+
+	for name, opts := range map[string]Options{"go-git": {Logger: logging.Discard}, "binary": {UseGitBinary: true, Logger: logging.Discard}} {
+		t.Run(name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			// A full SHA is not resolved, so the missing commit only fails the checkout
+			component := config.ComponentConfig{Distributor: "git", Name: repoDir, Version: strings.Repeat("0", 40)}
+
+			if _, err := newTestDownloader(t, opts).DownloadComponent(t.Context(), component, targetDir, "REL1_43"); err == nil {
+				t.Fatal("Expected error for a missing commit, got nil")
+			}
+
+			entries, err := os.ReadDir(targetDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("Expected a failed checkout to leave nothing behind, got %v", entries)
+			}
+		})
+	}
+}
+
+func TestResolveGitRefUnknown(t *testing.T) {
+	repoDir, _, _ := createTestRepo(t)
+
+	for _, backend := range []gitBackend{newGoGit(nil, logging.Discard), execGit{logger: logging.Discard}} {
+		if _, err := backend.resolveRef(t.Context(), repoDir, "does-not-exist", nil); err == nil {
+			t.Errorf("Expected error for unknown ref with %T, got nil", backend)
+		}
+	}
+}
+
+func TestGitHTTPClientPerDownloader(t *testing.T) {
+	repoDir, _, second := createTestRepo(t)
+	gitPath, _ := exec.LookPath("git")
+
+	// Serve the repository over HTTP and record the User-Agent of every request
+	var mu sync.Mutex
+	var agents []string
+	backend := &cgi.Handler{
+		Path: gitPath,
+		Args: []string{"http-backend"},
+		Env:  []string{"GIT_PROJECT_ROOT=" + filepath.Dir(repoDir), "GIT_HTTP_EXPORT_ALL=1"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents = append(agents, r.Header.Get("User-Agent"))
+		mu.Unlock()
+		backend.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	component := config.ComponentConfig{Distributor: "git", Name: server.URL + "/" + filepath.Base(repoDir), Version: "main"}
+	for _, binary := range []bool{false, true} {
+		for _, suffix := range []string{"first/1.0", "second/1.0"} {
+			client, err := httputil.NewClient(httputil.ClientOptions{UserAgentSuffix: suffix, Logger: logging.Discard})
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := newTestDownloader(t, Options{HTTPClient: client, UseGitBinary: binary, Logger: logging.Discard})
+
+			mu.Lock()
+			agents = nil
+			mu.Unlock()
+			result, err := d.Resolve(t.Context(), component, "REL1_43")
+			if err != nil {
+				t.Fatalf("Failed to resolve over HTTP (git binary: %v): %v", binary, err)
+			}
+			if result.Commit != second {
+				t.Errorf("Expected commit %s, got %s", second, result.Commit)
+			}
+			mu.Lock()
+			if len(agents) == 0 || !strings.HasSuffix(agents[0], suffix) {
+				t.Errorf("Expected the requests to use the client of the downloader with %q (git binary: %v), got %q", suffix, binary, agents)
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+func TestHTTPEnvCABundle(t *testing.T) {
+	dir := t.TempDir()
+	system := filepath.Join(dir, "system.pem")
+	extra := filepath.Join(dir, "extra.pem")
+	if err := os.WriteFile(system, []byte("system"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(extra, []byte("extra\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_SSL_CAINFO", "")
+	t.Setenv("SSL_CERT_FILE", system)
+
+	env, cleanup, err := httpEnv(httputil.ClientOptions{CAFiles: []string{extra}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env) != 1 || !strings.HasPrefix(env[0], "GIT_SSL_CAINFO=") {
+		t.Fatalf("Expected GIT_SSL_CAINFO, got %q", env)
+	}
+	bundle := strings.TrimPrefix(env[0], "GIT_SSL_CAINFO=")
+	data, err := os.ReadFile(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "system\nextra\n" {
+		t.Errorf("Expected the system and extra certificates in the bundle, got %q", data)
+	}
+
+	cleanup()
+	if _, err := os.Stat(bundle); !os.IsNotExist(err) {
+		t.Errorf("Expected the bundle to be removed, got %v", err)
+	}
+
+	if _, _, err := httpEnv(httputil.ClientOptions{CAFiles: []string{filepath.Join(dir, "missing.pem")}}); err == nil {
+		t.Error("Expected an error for a missing CA file")
+	}
+}
+
+func TestDownloadFromGitAttributes(t *testing.T) {
+	repoDir, _, _ := createTestRepo(t)
+	targetDir := t.TempDir()
+
+	component := config.ComponentConfig{Distributor: "git", Name: repoDir, Version: "main", Dir: "Custom", Exclude: []string{"file.txt"}}
+	result, err := newTestDownloader(t, Options{}).DownloadComponent(t.Context(), component, targetDir, "REL1_43")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.Dir != filepath.Join(targetDir, "Custom") {
+		t.Errorf("Expected component directory %s, got %s", filepath.Join(targetDir, "Custom"), result.Dir)
+	}
+
+	if _, err := os.Stat(filepath.Join(result.Dir, "file.txt")); !os.IsNotExist(err) {
+		t.Error("Expected excluded file to be removed")
+	}
+}
+
+func TestDownloadFromGitSubmodules(t *testing.T) {
+	subDir, _, _ := createTestRepo(t)
+	parentDir := filepath.Join(t.TempDir(), "Parent.git")
+	if err := os.MkdirAll(parentDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch=main"},
+		{"-c", "protocol.file.allow=always", "submodule", "--quiet", "add", subDir, "Example"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "add submodule"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = parentDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+
+	component := config.ComponentConfig{Distributor: "git", Name: parentDir, Version: "main", Submodules: true}
+	result, err := newTestDownloader(t, Options{}).DownloadComponent(t.Context(), component, t.TempDir(), "REL1_43")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(result.Dir, "Example", "file.txt")); err != nil || string(content) != "second" {
+		t.Errorf("Expected the submodule to be checked out, got %q, %v", content, err)
+	}
+}
+
+func TestSubmoduleCredentialsStayOnHost(t *testing.T) {
+	parent := "https://git.example.com/team/parent.git"
+	tests := []struct {
+		url      string
+		resolved string
+		sameHost bool
+	}{
+		{"../lib.git", "https://git.example.com/team/lib.git", true},
+		{"https://git.example.com/other/lib.git", "https://git.example.com/other/lib.git", true},
+		{"https://github.com/example/lib.git", "https://github.com/example/lib.git", false},
+		{"git@github.com:example/lib.git", "git@github.com:example/lib.git", false},
+	}
+	for _, test := range tests {
+		resolved := submoduleURL(parent, test.url)
+		if resolved != test.resolved {
+			t.Errorf("Expected %s to resolve to %s, got %s", test.url, test.resolved, resolved)
+		}
+		if sameHost(parent, resolved) != test.sameHost {
+			t.Errorf("Expected sameHost(%s, %s) to be %v", parent, resolved, test.sameHost)
+		}
+	}
+}
+
+func TestLookupNetrc(t *testing.T) {
+	netrcPath := filepath.Join(t.TempDir(), ".netrc")
+	content := `machine github.com
+  login alice
+  password secret
+machine gitlab.example.com login bob password hunter2
+default login anonymous password guest
+`
+	if err := os.WriteFile(netrcPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host, login, password string
+	}{
+		{"github.com", "alice", "secret"},
+		{"gitlab.example.com", "bob", "hunter2"},
+		{"other.example.com", "anonymous", "guest"},
+	}
+
+	for _, test := range tests {
+		login, password, err := lookupNetrc(netrcPath, test.host)
+		if err != nil {
+			t.Fatalf("Unexpected error for host %s: %v", test.host, err)
+		}
+		if login != test.login || password != test.password {
+			t.Errorf("Expected %s/%s for host %s, got %s/%s", test.login, test.password, test.host, login, password)
+		}
+	}
+}
+
+func TestAuthMethodSSHUser(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", keyPath).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen failed: %v: %s", err, out)
+	}
+	creds := &gitCredentials{SSHKeyPath: keyPath}
+
+	tests := map[string]string{
+		"ssh://deploy@git.example.com/repo.git": "deploy",
+		"builder@git.example.com:team/repo.git": "builder",
+		"git@github.com:example/repo.git":       "git",
+		"ssh://git.example.com:2222/repo.git":   "git",
+	}
+	for repoURL, user := range tests {
+		auth, err := creds.authMethod(repoURL)
+		if err != nil {
+			t.Fatalf("Failed to load key for %s: %v", repoURL, err)
+		}
+		if keys, ok := auth.(*gitssh.PublicKeys); !ok || keys.User != user {
+			t.Errorf("Expected user %s for %s, got %+v", user, repoURL, auth)
+		}
+	}
+}
+
+func TestGitCredentialsEnvKeepsGitConfig(t *testing.T) {
+	creds := &gitCredentials{Username: "user", Password: "token", RepoURL: "https://git.example.com/repo.git"}
+
+	env := creds.env([]string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=core.autocrlf", "GIT_CONFIG_VALUE_0=false"})
+	if len(env) != 3 || env[0] != "GIT_CONFIG_COUNT=2" || env[1] != "GIT_CONFIG_KEY_1=http.https://git.example.com/repo.git.extraHeader" || !strings.HasPrefix(env[2], "GIT_CONFIG_VALUE_1=Authorization: Basic ") {
+		t.Errorf("Expected the header to be added after the existing entry, got %q", env)
+	}
+
+	if env := creds.env(nil); env[0] != "GIT_CONFIG_COUNT=1" || env[1] != "GIT_CONFIG_KEY_0=http.https://git.example.com/repo.git.extraHeader" {
+		t.Errorf("Expected the header to be the only entry, got %q", env)
+	}
+}
+
+func TestGitCredentialsEnvScopedToRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+	creds := &gitCredentials{Username: "user", Password: "token", RepoURL: "https://git.example.com/team/repo.git"}
+
+	// git config --get-urlmatch applies the same URL matching as fetches do
+	for url, sent := range map[string]bool{
+		"https://git.example.com/team/repo.git/info/refs": true,
+		"https://git.example.com/team/other.git":          false,
+		"https://cdn.example.net/team/repo.git":           false,
+	} {
+		cmd := exec.Command("git", "config", "--get-urlmatch", "http.extraHeader", url)
+		cmd.Env = append(os.Environ(), creds.env(nil)...)
+		out, _ := cmd.Output()
+		if got := strings.HasPrefix(string(out), "Authorization: Basic "); got != sent {
+			t.Errorf("Expected the header to be sent to %s: %v, got %q", url, sent, out)
+		}
+	}
+}
+
+func TestGitCredentialsEnvQuotesSSHKey(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	keyPath := `/home/deploy/it's $HOME/` + "`id`" + `\key`
+	env := (&gitCredentials{SSHKeyPath: keyPath}).env(nil)
+
+	// Run the command with printf instead of ssh to see the arguments the shell passes
+	command := strings.Replace(strings.TrimPrefix(env[0], "GIT_SSH_COMMAND="), "ssh", `printf '%s\n'`, 1)
+	out, err := exec.Command("sh", "-c", command).Output()
+	if err != nil {
+		t.Fatalf("Failed to run %s: %v", command, err)
+	}
+	if args := strings.Split(string(out), "\n"); len(args) < 2 || args[1] != keyPath {
+		t.Errorf("Expected the key path %q to reach ssh unchanged, got %q", keyPath, out)
+	}
+}
+
+func TestWrapGitErrorAuthentication(t *testing.T) {
+	err := wrapGitError("https://example.com/repo.git", errors.New("exit status 128: fatal: could not read Username for 'https://example.com': terminal prompts disabled"))
+	if !errors.Is(err, ErrGitAuthentication) {
+		t.Errorf("Expected ErrGitAuthentication, got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package downloader
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// ErrGitAuthentication is returned when a Git server rejects or requires credentials
+var ErrGitAuthentication = errors.New("git authentication failed")
+
+// gitAuthFailures are fragments of git error output that indicate missing or rejected credentials
+var gitAuthFailures = []string{
+	"authentication failed",
+	"could not read username",
+	"could not read password",
+	"terminal prompts disabled",
+	"permission denied (publickey",
+	"host key verification failed",
+	"repository not found",
+	"the requested url returned error: 401",
+	"the requested url returned error: 403",
+}
+
+// gitCredentials holds credentials resolved from a component's auth attribute
+type gitCredentials struct {
+	Username   string
+	Password   string
+	SSHKeyPath string
+	RepoURL    string // repository the username and password are sent to
+}
+
+// resolveGitCredentials resolves the auth attribute of a component for the given repository URL.
+// It returns nil when no credentials are configured.
+func resolveGitCredentials(auth, repoURL string) (*gitCredentials, error) {
+	if auth == "" {
+		return nil, nil
+	}
+
+	kind, arg, _ := strings.Cut(auth, ":")
+	switch kind {
+	case "env":
+		value := os.Getenv(arg)
+		if value == "" {
+			return nil, fmt.Errorf("environment variable %s for git credentials is not set", arg)
+		}
+		// The variable holds either "user:token" or just a token
+		username, password, found := strings.Cut(value, ":")
+		if !found {
+			username, password = "git", value
+		}
+		return &gitCredentials{Username: username, Password: password, RepoURL: repoURL}, nil
+
+	case "netrc":
+		path := arg
+		if path == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("failed to locate home directory for .netrc: %w", err)
+			}
+			path = filepath.Join(home, ".netrc")
+		}
+
+		parsed, err := url.Parse(repoURL)
+		if err != nil || parsed.Hostname() == "" {
+			return nil, fmt.Errorf("netrc credentials require an HTTP(S) repository URL, got %s", repoURL)
+		}
+
+		username, password, err := lookupNetrc(path, parsed.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		return &gitCredentials{Username: username, Password: password, RepoURL: repoURL}, nil
+
+	case "ssh":
+		if _, err := os.Stat(arg); err != nil {
+			return nil, fmt.Errorf("ssh key %s is not readable: %w", arg, err)
+		}
+		return &gitCredentials{SSHKeyPath: arg}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported git auth %q", auth)
+}
+
+// env returns the environment variables that pass the credentials to the git binary
+// without exposing them on the command line. The header is only sent to URLs of the repository,
+// not to submodules or redirects on other hosts, and is added after the GIT_CONFIG_* entries of
+// environ, so that those still apply.
+func (c *gitCredentials) env(environ []string) []string {
+	if c == nil {
+		return nil
+	}
+
+	if c.SSHKeyPath != "" {
+		// git runs the command through the shell
+		return []string{"GIT_SSH_COMMAND=ssh -i " + shellQuote(c.SSHKeyPath) + " -o IdentitiesOnly=yes -o BatchMode=yes"}
+	}
+
+	// Later entries of the environment win, so the last count is the one git sees
+	count := 0
+	for _, variable := range environ {
+		if value, found := strings.CutPrefix(variable, "GIT_CONFIG_COUNT="); found {
+			count, _ = strconv.Atoi(value)
+		}
+	}
+
+	token := base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + c.Password))
+	return []string{
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", count+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=http.%s.extraHeader", count, c.RepoURL),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=Authorization: Basic %s", count, token),
+	}
+}
+
+// shellQuote quotes s as a single word for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// lookupNetrc returns the login and password for host from a .netrc file
+func lookupNetrc(path, host string) (string, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open netrc file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Split(bufio.ScanWords)
+
+	var tokens []string
+	for scanner.Scan() {
+		tokens = append(tokens, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", fmt.Errorf("failed to read netrc file: %w", err)
+	}
+
+	var login, password, defaultLogin, defaultPassword string
+	matched, inDefault := false, false
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			if matched {
+				return login, password, nil
+			}
+			inDefault = false
+			if i+1 < len(tokens) {
+				i++
+				matched = tokens[i] == host
+			}
+		case "default":
+			if matched {
+				return login, password, nil
+			}
+			inDefault = true
+		case "login", "password":
+			if i+1 >= len(tokens) {
+				continue
+			}
+			i++
+			switch {
+			case matched && tokens[i-1] == "login":
+				login = tokens[i]
+			case matched:
+				password = tokens[i]
+			case inDefault && tokens[i-1] == "login":
+				defaultLogin = tokens[i]
+			case inDefault:
+				defaultPassword = tokens[i]
+			}
+		}
+	}
+
+	if matched {
+		return login, password, nil
+	}
+	if defaultLogin != "" || defaultPassword != "" {
+		return defaultLogin, defaultPassword, nil
+	}
+
+	return "", "", fmt.Errorf("no credentials for %s in %s", host, path)
+}
+
+// wrapGitError converts git failures caused by missing or rejected credentials into ErrGitAuthentication
+func wrapGitError(repoURL string, err error) error {
+	if errors.Is(err, transport.ErrAuthenticationRequired) ||
+		errors.Is(err, transport.ErrAuthorizationFailed) ||
+		errors.Is(err, transport.ErrRepositoryNotFound) {
+		return fmt.Errorf("%w for %s (set auth=env:VAR, auth=netrc or auth=ssh:/path/to/key on the component): %v",
+			ErrGitAuthentication, repoURL, err)
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, fragment := range gitAuthFailures {
+		if strings.Contains(message, fragment) {
+			return fmt.Errorf("%w for %s (set auth=env:VAR, auth=netrc or auth=ssh:/path/to/key on the component): %v",
+				ErrGitAuthentication, repoURL, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,318 @@
+package extdist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SKevo18/mediawiki-updater/internal/httputil"
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+)
+
+// APIURL is the MediaWiki API that serves the ExtensionDistributor metadata
+const APIURL = "https://www.mediawiki.org/w/api.php"
+
+// batchSize is the maximum number of names the API accepts in one request
+const batchSize = 50
+
+// ErrNotFound is returned if a component or branch is not distributed by ExtDist
+var ErrNotFound = errors.New("not found on ExtDist")
+
+// Archive is a snapshot of a component branch distributed by ExtDist
+type Archive struct {
+	Type   string // "extension" or "skin"
+	Name   string
+	Branch string // e.g. "REL1_43" or "master"
+	Hash   string // abbreviated commit hash of the snapshot
+	URL    string
+	Date   time.Time // modification time from the directory listing, zero if unknown
+}
+
+// Client looks up ExtDist archives through the extdistrepos and extdistbranches API modules,
+// falling back to the directory listing of archives.
+// Results are cached, so every component is fetched at most once per client.
+type Client struct {
+	apiURLs     []string         // API mirrors in order, empty if the API is disabled
+	index       httputil.Mirrors // directory listing mirrors in order
+	customIndex bool             // whether the directory listing mirrors were configured
+	http        *httputil.Client
+	logger      logging.Logger
+
+	mu           sync.Mutex
+	repositories map[string][]string            // component names by type
+	branches     map[string]map[string]*Archive // archives by "type/name" and branch
+	archives     map[string][]*Archive          // archives in the directory listing by type
+}
+
+// Options contains configuration options for the client
+type Options struct {
+	// APIURLs are MediaWiki APIs to query in order, APIURL if empty. A single "none" disables
+	// the API, so archives are only looked up in the directory listing.
+	APIURLs []string
+	// IndexURLs are mirrors of the directory listing in order, IndexURL if empty. Archives
+	// found through the API are downloaded from these mirrors if they are set.
+	IndexURLs []string
+	// HTTPClient sends the requests; nil uses a client with the default settings
+	HTTPClient *httputil.Client
+	// Logger receives debug messages about unexpected responses; nil prints messages to standard output
+	Logger logging.Logger
+}
+
+// NewClient creates a new Client instance
+func NewClient(opts Options) *Client {
+	apiURLs := opts.APIURLs
+	if len(apiURLs) == 0 {
+		apiURLs = []string{APIURL}
+	}
+	if len(apiURLs) == 1 && apiURLs[0] == "none" {
+		apiURLs = nil
+	}
+
+	index := httputil.Mirrors(opts.IndexURLs)
+	if len(index) == 0 {
+		index = httputil.Mirrors{IndexURL}
+	}
+
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = httputil.DefaultClient()
+	}
+
+	return &Client{
+		apiURLs:      apiURLs,
+		index:        index,
+		customIndex:  len(opts.IndexURLs) > 0,
+		http:         httpClient,
+		logger:       logging.OrStdout(opts.Logger),
+		repositories: make(map[string][]string),
+		branches:     make(map[string]map[string]*Archive),
+		archives:     make(map[string][]*Archive),
+	}
+}
+
+// URLs returns the locations to download an archive from, in order. If directory listing mirrors
+// were configured, these are the archive on every mirror; otherwise the archive URL only.
+func (c *Client) URLs(archive *Archive) []string {
+	if !c.customIndex {
+		return []string{archive.URL}
+	}
+
+	urls := c.index.URLs(archive.Type + "s/" + path.Base(archive.URL))
+	// Start with the mirror the archive was listed on, if any
+	if i := slices.Index(urls, archive.URL); i > 0 {
+		urls = append([]string{archive.URL}, slices.Delete(urls, i, i+1)...)
+	}
+	return urls
+}
+
+// Repositories returns the sorted names of all extensions or skins distributed by ExtDist.
+// If the API is unavailable, the names are read from the directory listing instead.
+func (c *Client) Repositories(ctx context.Context, componentType string) ([]string, error) {
+	names, err := c.apiRepositories(ctx, componentType)
+	if err == nil {
+		return names, nil
+	}
+
+	archives, indexErr := c.fetchIndex(ctx, componentType)
+	if indexErr != nil {
+		return nil, fmt.Errorf("%w (directory listing: %v)", err, indexErr)
+	}
+	for _, archive := range archives {
+		if !slices.Contains(names, archive.Name) {
+			names = append(names, archive.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// apiRepositories fetches the names of all extensions and skins from the API into the cache
+func (c *Client) apiRepositories(ctx context.Context, componentType string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if names, ok := c.repositories[componentType]; ok {
+		return names, nil
+	}
+
+	var response struct {
+		Query struct {
+			Repos map[string][]string `json:"extdistrepos"`
+		} `json:"query"`
+	}
+	if err := c.query(ctx, url.Values{"meta": {"extdistrepos"}}, &response); err != nil {
+		return nil, err
+	}
+
+	for key, names := range response.Query.Repos {
+		sort.Strings(names)
+		c.repositories[strings.TrimSuffix(key, "s")] = names
+	}
+	return c.repositories[componentType], nil
+}
+
+// Branches returns the archives of every branch of the named components, keyed by name and
+// branch. Components that are not cached yet are fetched in as few requests as possible;
+// components that ExtDist does not distribute are missing from the result.
+func (c *Client) Branches(ctx context.Context, componentType string, names ...string) (map[string]map[string]*Archive, error) {
+	if len(c.apiURLs) == 0 {
+		return c.indexBranches(ctx, componentType, names)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var missing []string
+	for _, name := range names {
+		if _, ok := c.branches[componentKey(componentType, name)]; !ok && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+
+	for start := 0; start < len(missing); start += batchSize {
+		batch := missing[start:min(start+batchSize, len(missing))]
+		if err := c.fetchBranches(ctx, componentType, batch); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make(map[string]map[string]*Archive)
+	for _, name := range names {
+		if archives := c.branches[componentKey(componentType, name)]; len(archives) > 0 {
+			result[name] = archives
+		}
+	}
+	return result, nil
+}
+
+// Archive returns the archive of a component branch. The name must match exactly; if it is
+// unknown, the error suggests similar names. If the API is unavailable, the archive is looked
+// up in the directory listing instead.
+func (c *Client) Archive(ctx context.Context, componentType, name, branch string) (*Archive, error) {
+	branches, err := c.Branches(ctx, componentType, name)
+	if err != nil {
+		archive, indexErr := c.indexArchive(ctx, componentType, name, branch)
+		if errors.Is(indexErr, ErrNotFound) {
+			return nil, indexErr
+		}
+		if indexErr != nil {
+			return nil, fmt.Errorf("%w (directory listing: %v)", err, indexErr)
+		}
+		return archive, nil
+	}
+
+	archives, ok := branches[name]
+	if !ok {
+		// Suggestions are best effort, so a failure to list the repositories is ignored
+		names, _ := c.Repositories(ctx, componentType)
+		return nil, notFoundError(componentType, name, names)
+	}
+
+	archive, ok := archives[branch]
+	if !ok {
+		return nil, fmt.Errorf("%s %s branch %s: %w (available: %s)", componentType, name, branch, ErrNotFound, strings.Join(sortedBranches(archives), ", "))
+	}
+	return archive, nil
+}
+
+// fetchBranches fetches the archives of a batch of components into the cache. Archives that do
+// not match their component and branch are skipped, so that one odd entry does not hide the rest.
+func (c *Client) fetchBranches(ctx context.Context, componentType string, names []string) error {
+	param := "edbexts"
+	if componentType == "skin" {
+		param = "edbskins"
+	}
+
+	var response struct {
+		Query struct {
+			Branches map[string]map[string]map[string]string `json:"extdistbranches"`
+		} `json:"query"`
+	}
+	if err := c.query(ctx, url.Values{"list": {"extdistbranches"}, param: {strings.Join(names, "|")}}, &response); err != nil {
+		return err
+	}
+
+	byName := response.Query.Branches[componentType+"s"]
+	for _, name := range names {
+		archives := make(map[string]*Archive)
+		for branch, archiveURL := range byName[name] {
+			archiveName, archiveBranch, hash, ok := ParseArchiveName(archiveURL)
+			if !ok || archiveName != name || archiveBranch != branch {
+				logging.Debugf(c.logger, "    Skipping ExtDist archive %s for %s %s branch %s\n", path.Base(archiveURL), componentType, name, branch)
+				continue
+			}
+			archives[branch] = &Archive{
+				Type:   componentType,
+				Name:   name,
+				Branch: branch,
+				Hash:   hash,
+				URL:    archiveURL,
+			}
+		}
+		// Components without archives are cached too, so they are not requested again
+		c.branches[componentKey(componentType, name)] = archives
+	}
+	return nil
+}
+
+// query performs an API query and decodes the JSON response into v
+func (c *Client) query(ctx context.Context, params url.Values, v any) error {
+	params.Set("action", "query")
+	params.Set("format", "json")
+	params.Set("formatversion", "2")
+
+	if len(c.apiURLs) == 0 {
+		return fmt.Errorf("ExtDist API is disabled")
+	}
+
+	urls := make([]string, 0, len(c.apiURLs))
+	for _, apiURL := range c.apiURLs {
+		urls = append(urls, apiURL+"?"+params.Encode())
+	}
+	resp, _, err := c.http.GetFirst(ctx, urls)
+	if err != nil {
+		return fmt.Errorf("failed to query ExtDist: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Error *struct {
+			Code string `json:"code"`
+			Info string `json:"info"`
+		} `json:"error"`
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return fmt.Errorf("invalid ExtDist response: %w", err)
+	}
+	if err := json.Unmarshal(raw, &envelope); err == nil && envelope.Error != nil {
+		return fmt.Errorf("ExtDist query failed: %s: %s", envelope.Error.Code, envelope.Error.Info)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("invalid ExtDist response: %w", err)
+	}
+	return nil
+}
+
+// componentKey returns the cache key of a component, e.g. "extension/Math"
+func componentKey(componentType, name string) string {
+	return componentType + "/" + name
+}
+
+// sortedBranches returns the branch names of archives in sorted order
+func sortedBranches(archives map[string]*Archive) []string {
+	branches := make([]string, 0, len(archives))
+	for branch := range archives {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+	return branches
+}
@@ -0,0 +1,264 @@
+package extdist
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testIndex is a directory listing with two snapshots of the same branch
+const testIndex = `<html><body><pre>
+<a href="../">../</a>
+<a href="Math-REL1_43-0aaaaaa.tar.gz">Math-REL1_43-0aaaaaa.tar.gz</a>    01-Oct-2024 12:00    1234
+<a href="Math-REL1_43-fbbbbbb.tar.gz">Math-REL1_43-fbbbbbb.tar.gz</a>    15-Sep-2024 08:30    1234
+<a href="Math-master-0c9d8e7.tar.gz">Math-master-0c9d8e7.tar.gz</a>    02-Oct-2024 09:00    1234
+<a href="MathSearch-REL1_43-1234567.tar.gz">MathSearch-REL1_43-1234567.tar.gz</a>    01-Oct-2024 12:00    1234
+</pre></body></html>`
+
+// newTestAPI serves the extdistrepos and extdistbranches API modules and the directory listing,
+// and counts the requests. If the API is broken, it fails with status 503.
+func newTestAPI(t *testing.T, broken bool) (*Client, *int) {
+	t.Helper()
+
+	archives := map[string]map[string]string{
+		"Math":       {"REL1_43": "Math-REL1_43-6ef1a2b.tar.gz", "master": "Math-master-0c9d8e7.tar.gz"},
+		"MathSearch": {"REL1_43": "MathSearch-REL1_43-1234567.tar.gz"},
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/dist/extensions/" {
+			fmt.Fprint(w, testIndex)
+			return
+		}
+		if broken {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		query := r.URL.Query()
+		if query.Get("action") != "query" || query.Get("format") != "json" {
+			t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+		}
+
+		switch {
+		case query.Get("meta") == "extdistrepos":
+			fmt.Fprint(w, `{"query": {"extdistrepos": {"extensions": ["MathSearch", "Math"], "skins": ["Vector"]}}}`)
+		case query.Get("list") == "extdistbranches" && query.Has("edbexts"):
+			var entries []string
+			for _, name := range strings.Split(query.Get("edbexts"), "|") {
+				var branches []string
+				for branch, filename := range archives[name] {
+					branches = append(branches, fmt.Sprintf(`%q: "https://extdist.example/dist/extensions/%s"`, branch, filename))
+				}
+				if branches != nil {
+					entries = append(entries, fmt.Sprintf(`%q: {%s}`, name, strings.Join(branches, ", ")))
+				}
+			}
+			fmt.Fprintf(w, `{"query": {"extdistbranches": {"extensions": {%s}}}}`, strings.Join(entries, ", "))
+		default:
+			fmt.Fprint(w, `{"error": {"code": "badvalue", "info": "Unrecognized value"}}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return NewClient(Options{APIURLs: []string{server.URL + "/w/api.php"}, IndexURLs: []string{server.URL + "/dist"}}), &requests
+}
+
+func TestArchive(t *testing.T) {
+	client, requests := newTestAPI(t, false)
+
+	if _, err := client.Branches(t.Context(), "extension", "Math", "MathSearch", "Missing", "math"); err != nil {
+		t.Fatalf("Failed to fetch branches: %v", err)
+	}
+
+	archive, err := client.Archive(t.Context(), "extension", "Math", "REL1_43")
+	if err != nil {
+		t.Fatalf("Failed to look up Math: %v", err)
+	}
+	expected := Archive{
+		Type:   "extension",
+		Name:   "Math",
+		Branch: "REL1_43",
+		Hash:   "6ef1a2b",
+		URL:    "https://extdist.example/dist/extensions/Math-REL1_43-6ef1a2b.tar.gz",
+	}
+	if *archive != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *archive)
+	}
+
+	if _, err := client.Archive(t.Context(), "extension", "Math", "REL1_39"); !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "available: REL1_43, master") {
+		t.Errorf("Expected missing branch to list the available ones, got %v", err)
+	}
+	if _, err := client.Archive(t.Context(), "extension", "Missing", "REL1_43"); !errors.Is(err, ErrNotFound) || strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("Expected ErrNotFound without suggestions for a missing extension, got %v", err)
+	}
+	if _, err := client.Archive(t.Context(), "extension", "math", "REL1_43"); err == nil || !strings.HasSuffix(err.Error(), "(did you mean Math, MathSearch?)") {
+		t.Errorf("Expected suggestions for a misspelled extension, got %v", err)
+	}
+
+	// One request for the branches, one for the names to suggest
+	if *requests != 2 {
+		t.Errorf("Expected 2 requests for all lookups, got %d", *requests)
+	}
+}
+
+// debugLogger records the debug messages passed to it
+type debugLogger struct {
+	messages []string
+}
+
+func (l *debugLogger) Printf(string, ...any) {}
+
+func (l *debugLogger) Debugf(format string, args ...any) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func TestBranchesSkipsUnexpectedArchives(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"query": {"extdistbranches": {"extensions": {
+			"Math": {"REL1_43": "https://extdist.example/dist/extensions/Math-REL1_43-6ef1a2b.tar.gz", "REL1_42": "https://extdist.example/dist/extensions/Math-REL1_42.zip"},
+			"Cite": {"REL1_43": "https://extdist.example/dist/extensions/Cite-REL1_43-1234567.tar.gz"}
+		}}}}`)
+	}))
+	defer server.Close()
+
+	logger := &debugLogger{}
+	client := NewClient(Options{APIURLs: []string{server.URL}, Logger: logger})
+
+	branches, err := client.Branches(t.Context(), "extension", "Math", "Cite")
+	if err != nil {
+		t.Fatalf("Expected an unexpected archive name to be skipped, got %v", err)
+	}
+	if len(branches["Math"]) != 1 || branches["Math"]["REL1_43"] == nil || branches["Cite"]["REL1_43"] == nil {
+		t.Errorf("Unexpected branches: %+v", branches)
+	}
+	if len(logger.messages) != 1 || !strings.Contains(logger.messages[0], "Math-REL1_42.zip") {
+		t.Errorf("Expected the skipped archive to be logged, got %q", logger.messages)
+	}
+}
+
+func TestRepositories(t *testing.T) {
+	client, requests := newTestAPI(t, false)
+
+	for range 2 {
+		names, err := client.Repositories(t.Context(), "extension")
+		if err != nil {
+			t.Fatalf("Failed to list extensions: %v", err)
+		}
+		if strings.Join(names, ",") != "Math,MathSearch" {
+			t.Errorf("Unexpected extensions: %v", names)
+		}
+	}
+
+	if skins, _ := client.Repositories(t.Context(), "skin"); strings.Join(skins, ",") != "Vector" {
+		t.Errorf("Unexpected skins: %v", skins)
+	}
+	if *requests != 1 {
+		t.Errorf("Expected the repositories to be fetched once, got %d requests", *requests)
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	client, _ := newTestAPI(t, false)
+
+	if _, err := client.Branches(t.Context(), "skin", "Vector"); err == nil || !strings.Contains(err.Error(), "badvalue") {
+		t.Errorf("Expected the API error to be returned, got %v", err)
+	}
+}
+
+func TestArchiveIndexFallback(t *testing.T) {
+	client, requests := newTestAPI(t, true)
+
+	archive, err := client.Archive(t.Context(), "extension", "Math", "REL1_43")
+	if err != nil {
+		t.Fatalf("Failed to look up Math in the directory listing: %v", err)
+	}
+	if archive.Hash != "0aaaaaa" || !strings.HasSuffix(archive.URL, "/dist/extensions/Math-REL1_43-0aaaaaa.tar.gz") {
+		t.Errorf("Expected the newest snapshot, got %+v", archive)
+	}
+
+	if _, err := client.Archive(t.Context(), "extension", "Mat", "REL1_43"); err == nil || !strings.Contains(err.Error(), "did you mean Math, MathSearch?") {
+		t.Errorf("Expected suggestions from the directory listing, got %v", err)
+	}
+	if _, err := client.Archive(t.Context(), "extension", "MathSearch", "master"); err == nil || !strings.Contains(err.Error(), "available: REL1_43") {
+		t.Errorf("Expected the available branches, got %v", err)
+	}
+
+	// Three failed API requests and a single fetch of the directory listing
+	if *requests != 4 {
+		t.Errorf("Expected the directory listing to be fetched once, got %d requests", *requests)
+	}
+}
+
+func TestArchiveLocalMirror(t *testing.T) {
+	mirror := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(mirror, "extensions"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mirror, "extensions", "Math-REL1_43-6ef1a2b.tar.gz"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(Options{APIURLs: []string{"none"}, IndexURLs: []string{"https://extdist.example/dist", mirror}})
+
+	// The unreachable mirror fails over to the local directory
+	archive, err := client.Archive(t.Context(), "extension", "Math", "REL1_43")
+	if err != nil {
+		t.Fatalf("Failed to look up Math in the local mirror: %v", err)
+	}
+	if expected := mirror + "/extensions/Math-REL1_43-6ef1a2b.tar.gz"; archive.URL != expected {
+		t.Errorf("Expected %s, got %s", expected, archive.URL)
+	}
+
+	urls := client.URLs(archive)
+	if len(urls) != 2 || urls[0] != archive.URL || urls[1] != "https://extdist.example/dist/extensions/Math-REL1_43-6ef1a2b.tar.gz" {
+		t.Errorf("Expected the listing mirror first, got %v", urls)
+	}
+}
+
+func TestParseArchiveName(t *testing.T) {
+	tests := []struct {
+		filename string
+		name     string
+		branch   string
+		hash     string
+	}{
+		{"Math-REL1_43-6ef1a2b.tar.gz", "Math", "REL1_43", "6ef1a2b"},
+		{"https://extdist.wmflabs.org/dist/extensions/CiteThisPage-master-0c9d8e7.tar.gz", "CiteThisPage", "master", "0c9d8e7"},
+		{"Semantic-MediaWiki-REL1_39-1234567.tar.gz", "Semantic-MediaWiki", "REL1_39", "1234567"},
+	}
+	for _, test := range tests {
+		name, branch, hash, ok := ParseArchiveName(test.filename)
+		if !ok || name != test.name || branch != test.branch || hash != test.hash {
+			t.Errorf("ParseArchiveName(%q) = %q, %q, %q, %v", test.filename, name, branch, hash, ok)
+		}
+	}
+
+	for _, filename := range []string{"Math-REL1_43.tar.gz", "Math-REL1_43-6ef1a2b.zip", "../"} {
+		if _, _, _, ok := ParseArchiveName(filename); ok {
+			t.Errorf("Expected %q not to parse", filename)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	names := []string{"Cite", "CiteThisPage", "Citoid", "Echo", "Math", "MathSearch"}
+	tests := map[string]string{
+		"cite":    "Cite,CiteThisPage",
+		"Mathh":   "Math",
+		"Ecko":    "Echo",
+		"Unknown": "",
+	}
+	for name, expected := range tests {
+		if actual := strings.Join(suggest(name, names), ","); actual != expected {
+			t.Errorf("suggest(%q) = %q, expected %q", name, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package extdist
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// IndexURL is the directory listing of ExtDist archives, used if the API is unavailable
+const IndexURL = "https://extdist.wmflabs.org/dist/"
+
+// archiveNamePattern matches archive filenames like Math-REL1_43-6ef1a2b.tar.gz. The name may
+// itself contain dashes, so the branch and hash are matched from the end.
+var archiveNamePattern = regexp.MustCompile(`^(.+)-(REL\d+_\d+|master|main)-([0-9a-f]{7,40})\.tar\.gz$`)
+
+// indexDatePatterns match the modification time next to an entry of an Apache or nginx directory listing
+var indexDatePatterns = []struct {
+	pattern *regexp.Regexp
+	layout  string
+}{
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}`), "2006-01-02 15:04"},
+	{regexp.MustCompile(`\d{2}-[A-Z][a-z]{2}-\d{4} \d{2}:\d{2}`), "02-Jan-2006 15:04"},
+}
+
+// ParseArchiveName splits an archive filename like Math-REL1_43-6ef1a2b.tar.gz into the component
+// name, branch and abbreviated commit hash
+func ParseArchiveName(filename string) (name, branch, hash string, ok bool) {
+	match := archiveNamePattern.FindStringSubmatch(path.Base(filename))
+	if match == nil {
+		return "", "", "", false
+	}
+	return match[1], match[2], match[3], true
+}
+
+// indexArchive looks up an archive in the directory listing. If several snapshots of the branch
+// are listed, the newest one is returned.
+func (c *Client) indexArchive(ctx context.Context, componentType, name, branch string) (*Archive, error) {
+	archives, err := c.fetchIndex(ctx, componentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	var candidates []*Archive
+	branches := make(map[string]*Archive)
+	for _, archive := range archives {
+		names = append(names, archive.Name)
+		if archive.Name != name {
+			continue
+		}
+		branches[archive.Branch] = archive
+		if archive.Branch == branch {
+			candidates = append(candidates, archive)
+		}
+	}
+
+	if len(branches) == 0 {
+		return nil, notFoundError(componentType, name, names)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%s %s branch %s: %w (available: %s)", componentType, name, branch, ErrNotFound, strings.Join(sortedBranches(branches), ", "))
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return newer(candidates[i], candidates[j])
+	})
+	return candidates[0], nil
+}
+
+// indexBranches returns the newest archive of every branch of the named components in the
+// directory listing, keyed by name and branch
+func (c *Client) indexBranches(ctx context.Context, componentType string, names []string) (map[string]map[string]*Archive, error) {
+	archives, err := c.fetchIndex(ctx, componentType)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]map[string]*Archive)
+	for _, archive := range archives {
+		if !slices.Contains(names, archive.Name) {
+			continue
+		}
+		if result[archive.Name] == nil {
+			result[archive.Name] = make(map[string]*Archive)
+		}
+		if current := result[archive.Name][archive.Branch]; current == nil || newer(archive, current) {
+			result[archive.Name][archive.Branch] = archive
+		}
+	}
+	return result, nil
+}
+
+// newer reports whether archive a is a newer snapshot than b, by date and then by hash
+func newer(a, b *Archive) bool {
+	if !a.Date.Equal(b.Date) {
+		return a.Date.After(b.Date)
+	}
+	return a.Hash > b.Hash
+}
+
+// fetchIndex fetches and parses the directory listing of a component type into the cache
+func (c *Client) fetchIndex(ctx context.Context, componentType string) ([]*Archive, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if archives, ok := c.archives[componentType]; ok {
+		return archives, nil
+	}
+
+	resp, indexURL, err := c.index.Get(ctx, c.http, componentType+"s/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ExtDist index: %w", err)
+	}
+	defer resp.Body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ExtDist index: %w", err)
+	}
+
+	var archives []*Archive
+	doc.Find("a").Each(func(i int, s *goquery.Selection) {
+		href := s.AttrOr("href", "")
+		name, branch, hash, ok := ParseArchiveName(href)
+		if !ok {
+			return
+		}
+
+		archives = append(archives, &Archive{
+			Type:   componentType,
+			Name:   name,
+			Branch: branch,
+			Hash:   hash,
+			URL:    indexURL + path.Base(href),
+			Date:   indexDate(s),
+		})
+	})
+
+	c.archives[componentType] = archives
+	return archives, nil
+}
+
+// indexDate reads the modification time printed after a link in a directory listing,
+// or returns the zero time if there is none
+func indexDate(link *goquery.Selection) time.Time {
+	var text string
+	if node := link.Get(0).NextSibling; node != nil {
+		text = node.Data
+	}
+	if text == "" || strings.TrimSpace(text) == "" {
+		// Table layouts put the date in the next cell
+		text = link.Closest("td").Next().Text()
+	}
+
+	for _, date := range indexDatePatterns {
+		if match := date.pattern.FindString(text); match != "" {
+			if parsed, err := time.Parse(date.layout, match); err == nil {
+				return parsed
+			}
+		}
+	}
+	return time.Time{}
+}
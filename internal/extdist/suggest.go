@@ -0,0 +1,84 @@
+package extdist
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions is the number of similar names listed when a component is not found
+const maxSuggestions = 5
+
+// notFoundError returns an ErrNotFound error for an unknown component, suggesting the names
+// from candidates that are closest to name
+func notFoundError(componentType, name string, candidates []string) error {
+	suggestions := suggest(name, candidates)
+	if len(suggestions) == 0 {
+		return fmt.Errorf("%s %s: %w", componentType, name, ErrNotFound)
+	}
+	return fmt.Errorf("%s %s: %w (did you mean %s?)", componentType, name, ErrNotFound, strings.Join(suggestions, ", "))
+}
+
+// suggest returns up to maxSuggestions names that differ from name only in case, share its
+// prefix or are within a small edit distance of it, closest first
+func suggest(name string, candidates []string) []string {
+	type scored struct {
+		name     string
+		distance int
+	}
+
+	lowerName := strings.ToLower(name)
+	maxDistance := max(2, len(name)/4)
+
+	var matches []scored
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if seen[candidate] || candidate == name {
+			continue
+		}
+		seen[candidate] = true
+
+		lowerCandidate := strings.ToLower(candidate)
+		distance := editDistance(lowerName, lowerCandidate)
+		if distance <= maxDistance || (len(lowerName) >= 3 && strings.HasPrefix(lowerCandidate, lowerName)) {
+			matches = append(matches, scored{candidate, distance})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	var suggestions []string
+	for _, match := range matches[:min(len(matches), maxSuggestions)] {
+		suggestions = append(suggestions, match.name)
+	}
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = slices.Min([]int{previous[j] + 1, current[j-1] + 1, previous[j-1] + cost})
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
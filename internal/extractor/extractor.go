@@ -18,15 +18,21 @@ func NewExtractor() *Extractor {
 	return &Extractor{}
 }
 
-// ExtractArchive extracts a tar.gz archive to the specified directory
-func (e *Extractor) ExtractArchive(reader io.Reader, targetDir string, renamer extract.Renamer) error {
-	return extract.Gz(context.TODO(), reader, targetDir, renamer)
+// ExtractArchive extracts a tar.gz archive to the specified directory, stopping when ctx is cancelled
+func (e *Extractor) ExtractArchive(ctx context.Context, reader io.Reader, targetDir string, renamer extract.Renamer) error {
+	return extract.Gz(ctx, reader, targetDir, renamer)
 }
 
 // ExtractMediaWikiCore extracts MediaWiki core with proper path manipulation
-func (e *Extractor) ExtractMediaWikiCore(reader io.Reader, targetDir string) error {
-	return e.ExtractArchive(reader, targetDir, func(path string) string {
-		// Remove the first directory component (e.g., mediawiki-1.43.1/)
+func (e *Extractor) ExtractMediaWikiCore(ctx context.Context, reader io.Reader, targetDir string) error {
+	return e.ExtractStripped(ctx, reader, targetDir)
+}
+
+// ExtractStripped extracts an archive whose contents are wrapped in a single top-level
+// directory (e.g., mediawiki-1.43.1/ or Math/) directly into targetDir
+func (e *Extractor) ExtractStripped(ctx context.Context, reader io.Reader, targetDir string) error {
+	return e.ExtractArchive(ctx, reader, targetDir, func(path string) string {
+		// Remove the first directory component
 		parts := strings.Split(path, string(filepath.Separator))
 		if len(parts) > 1 {
 			parts = parts[1:]
@@ -64,6 +70,16 @@ func (e *Extractor) CopyContents(src, dst string, ignorePaths []string) error {
 	})
 }
 
+// RemovePaths removes the given paths, relative to dir, if they exist
+func (e *Extractor) RemovePaths(dir string, paths []string) error {
+	for _, path := range paths {
+		if err := os.RemoveAll(filepath.Join(dir, filepath.FromSlash(path))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // copyFile copies a single file from source to destination
 func (e *Extractor) copyFile(src, dst string, mode os.FileMode) error {
 	srcFile, err := os.Open(src)
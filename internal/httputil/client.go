@@ -0,0 +1,198 @@
+package httputil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ClientOptions configures a Client
+type ClientOptions struct {
+	// Proxy is the proxy URL for all requests; if empty, HTTP_PROXY and HTTPS_PROXY are used
+	Proxy string
+	// NoProxy lists hosts, domains (".example.com"), IP addresses and CIDR ranges that bypass the
+	// proxy; if empty, NO_PROXY is used
+	NoProxy []string
+	// CAFiles are PEM files with certificate authorities trusted in addition to the system ones
+	CAFiles []string
+	// ClientCert and ClientKey are the PEM certificate and private key presented to servers that
+	// request a client certificate
+	ClientCert string
+	ClientKey  string
+	// UserAgentSuffix is appended to the User-Agent header
+	UserAgentSuffix string
+	// Limits throttle the requests to every host; HostLimits override them for specific hosts,
+	// keyed by host name with the port if the URL has one
+	Limits     Limits
+	HostLimits map[string]Limits
+	// MaxConcurrent caps the requests in flight at once; 0 uses the default and a negative
+	// value removes the cap
+	MaxConcurrent int
+	// MaxRetries is how often a request answered with 429 or 503 and a Retry-After header is
+	// retried; 0 uses the default and a negative value disables retries
+	MaxRetries int
+	// Doer sends all requests as they are if set, without the settings above
+	Doer Doer
+	// Logger receives the warnings about retried requests and failing mirrors; nil prints them
+	// to standard output
+	Logger logging.Logger
+}
+
+// Client sends HTTP requests with the settings of its options. file:// URLs and absolute local
+// paths are read from disk.
+type Client struct {
+	http   *http.Client
+	opts   ClientOptions
+	logger logging.Logger
+}
+
+// NewClient creates a Client. It fails if the proxy URL, CA files or client certificate of opts
+// are invalid.
+func NewClient(opts ClientOptions) (*Client, error) {
+	logger := logging.OrStdout(opts.Logger)
+	if opts.Doer != nil {
+		c, ok := opts.Doer.(*http.Client)
+		if !ok {
+			c = &http.Client{Transport: doerTransport{opts.Doer}}
+		}
+		return &Client{http: c, logger: logger}, nil
+	}
+
+	transport := newTransport()
+
+	proxy := httpproxy.FromEnvironment()
+	if opts.Proxy != "" {
+		if _, err := url.Parse(opts.Proxy); err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		proxy.HTTPProxy, proxy.HTTPSProxy = opts.Proxy, opts.Proxy
+	}
+	if len(opts.NoProxy) > 0 {
+		proxy.NoProxy = strings.Join(opts.NoProxy, ",")
+	}
+	proxyFunc := proxy.ProxyFunc()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+
+	if len(opts.CAFiles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, file := range opts.CAFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA file %s", file)
+			}
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		if opts.ClientCert == "" || opts.ClientKey == "" {
+			return nil, fmt.Errorf("a client certificate needs both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return newClient(transport, opts, logger), nil
+}
+
+// DefaultClient returns a new Client with the default settings
+func DefaultClient() *Client {
+	return newClient(newTransport(), ClientOptions{}, logging.Stdout)
+}
+
+// Doer sends HTTP requests. *http.Client implements it.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// doerTransport sends the requests of an http.Client through a Doer
+type doerTransport struct {
+	doer Doer
+}
+
+func (t doerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.doer.Do(req)
+}
+
+// HTTP returns the underlying HTTP client. Its transport honours the configured proxy,
+// certificate authorities and client certificate, throttles requests per host, and sets the
+// User-Agent header.
+func (c *Client) HTTP() *http.Client {
+	return c.http
+}
+
+// Options returns the options the client was created with. They are empty if it sends requests
+// through a Doer.
+func (c *Client) Options() ClientOptions {
+	return c.opts
+}
+
+// UserAgent returns the User-Agent header sent with every request
+func (o ClientOptions) UserAgent() string {
+	if o.UserAgentSuffix == "" {
+		return UserAgent
+	}
+	return UserAgent + " " + o.UserAgentSuffix
+}
+
+// userAgentTransport sets the User-Agent header of requests without one, and appends the
+// configured suffix to the User-Agent of requests that set their own, like Git over HTTP
+type userAgentTransport struct {
+	base   http.RoundTripper
+	agent  string
+	suffix string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	agent := req.Header.Get("User-Agent")
+	switch {
+	case agent == "":
+		agent = t.agent
+	case t.suffix != "" && !strings.HasSuffix(agent, t.suffix):
+		agent += " " + t.suffix
+	default:
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", agent)
+	return t.base.RoundTrip(req)
+}
+
+// newClient returns a client that sends requests through transport with the User-Agent and
+// limits of opts
+func newClient(transport *http.Transport, opts ClientOptions, logger logging.Logger) *Client {
+	limited := newLimitTransport(transport, opts, logger)
+	return &Client{
+		http:   &http.Client{Transport: &userAgentTransport{base: limited, agent: opts.UserAgent(), suffix: opts.UserAgentSuffix}},
+		opts:   opts,
+		logger: logger,
+	}
+}
+
+// newTransport returns a copy of the default transport with its own TLS configuration
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	return transport
+}
@@ -0,0 +1,156 @@
+package httputil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+)
+
+// newTestClient creates a client for a test that discards its warnings
+func newTestClient(t *testing.T, opts ClientOptions) *Client {
+	t.Helper()
+	opts.Logger = logging.Discard
+	c, err := NewClient(opts)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return c
+}
+
+// writePEM writes PEM blocks to a file in a temporary directory
+func writePEM(t *testing.T, name string, blocks ...*pem.Block) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	var data []byte
+	for _, block := range blocks {
+		data = append(data, pem.EncodeToMemory(block)...)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProxyAndUserAgent(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.URL.Host, r.Header.Get("User-Agent"))
+	}))
+	defer proxy.Close()
+
+	c := newTestClient(t, ClientOptions{Proxy: proxy.URL, NoProxy: []string{".internal.example"}, UserAgentSuffix: "corp/1.0"})
+
+	resp, err := c.Get(t.Context(), "http://releases.example/mediawiki/")
+	if err != nil {
+		t.Fatalf("Expected the request to go through the proxy, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if expected := "releases.example " + UserAgent + " corp/1.0"; string(body) != expected {
+		t.Errorf("Expected %q, got %q", expected, body)
+	}
+
+	// Hosts on the no-proxy list are requested directly, so the made-up host does not resolve
+	if _, err := c.Get(t.Context(), "http://mirror.internal.example/"); err == nil {
+		t.Error("Expected a host on the no-proxy list to bypass the proxy")
+	}
+}
+
+func TestCAFilesAndClientCertificate(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, len(r.TLS.PeerCertificates))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	// The server certificate doubles as the client certificate
+	serverCert := server.TLS.Certificates[0]
+	key, err := x509.MarshalPKCS8PrivateKey(serverCert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := writePEM(t, "cert.pem", &pem.Block{Type: "CERTIFICATE", Bytes: serverCert.Certificate[0]})
+	keyFile := writePEM(t, "key.pem", &pem.Block{Type: "PRIVATE KEY", Bytes: key})
+
+	c := newTestClient(t, ClientOptions{CAFiles: []string{certFile}})
+	if _, err := c.Get(t.Context(), server.URL); err == nil {
+		t.Error("Expected the server to reject a request without a client certificate")
+	}
+
+	c = newTestClient(t, ClientOptions{CAFiles: []string{certFile}, ClientCert: certFile, ClientKey: keyFile})
+	resp, err := c.Get(t.Context(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected the CA file and client certificate to be used, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "1" {
+		t.Errorf("Expected the server to see one client certificate, got %s", body)
+	}
+
+	for _, opts := range []ClientOptions{
+		{CAFiles: []string{keyFile}},
+		{ClientCert: certFile},
+		{CAFiles: []string{filepath.Join(t.TempDir(), "missing.pem")}},
+	} {
+		if _, err := NewClient(opts); err == nil {
+			t.Errorf("Expected %+v to be rejected", opts)
+		}
+	}
+}
+
+func TestGetFirstStopsOnTimeout(t *testing.T) {
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer stalled.Close()
+
+	tried := false
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tried = true
+	}))
+	defer fallback.Close()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	c := newTestClient(t, ClientOptions{})
+	if _, _, err := c.GetFirst(ctx, []string{stalled.URL, fallback.URL}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected a stalled download to time out, got %v", err)
+	}
+	if tried {
+		t.Error("Expected no further mirror to be tried after the timeout")
+	}
+}
+
+func TestGetLocalContentLength(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mediawiki-1.43.1.tar.gz"), make([]byte, 1234), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]int64{
+		filepath.Join(dir, "mediawiki-1.43.1.tar.gz"): 1234,
+		dir: -1,
+	} {
+		resp, err := DefaultClient().Get(t.Context(), path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.ContentLength != expected {
+			t.Errorf("Expected content length %d for %s, got %d", expected, path, resp.ContentLength)
+		}
+	}
+}
@@ -1,19 +1,28 @@
 package httputil
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
 
 const UserAgent = "mediawiki-updater (https://github.com/SKevo18/mediawiki-updater)"
 
-// Get performs an HTTP GET request with a proper User-Agent header.
-func Get(url string) (*http.Response, error) {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+// Get performs an HTTP GET request, which sets a proper User-Agent header. file:// URLs and
+// absolute local paths are read from disk, directories as an HTML listing.
+// Cancelling ctx aborts the request, including reading the response body.
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	if path, ok := localPath(url); ok {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return getLocal(path)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("User-Agent", UserAgent)
-	return http.DefaultClient.Do(req)
+	return c.http.Do(req)
 }
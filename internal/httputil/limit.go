@@ -0,0 +1,263 @@
+package httputil
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+)
+
+// Limits throttle the requests sent to a host with a token bucket
+type Limits struct {
+	// Rate is the number of requests per second; 0 uses the default and a negative rate disables the limit
+	Rate float64
+	// Burst is the number of requests that may be sent at once before Rate applies; 0 uses the default
+	Burst int
+}
+
+const (
+	// DefaultRate is the default number of requests per second to a host
+	DefaultRate = 5
+	// DefaultBurst is the default number of requests that may be sent to a host at once
+	DefaultBurst = 5
+	// DefaultMaxConcurrent is the default number of requests in flight at once
+	DefaultMaxConcurrent = 4
+	// DefaultMaxRetries is the default number of times a request is retried after a Retry-After response
+	DefaultMaxRetries = 3
+	// MaxRetryWait is the longest Retry-After delay that is waited for; longer delays fail the request
+	MaxRetryWait = 2 * time.Minute
+)
+
+// withDefaults returns the limits with unset fields replaced by the defaults
+func (l Limits) withDefaults() Limits {
+	if l.Rate == 0 {
+		l.Rate = DefaultRate
+	}
+	if l.Burst <= 0 {
+		l.Burst = DefaultBurst
+	}
+	return l
+}
+
+// bucket is a token bucket of a host
+type bucket struct {
+	limits Limits
+	tokens float64
+	last   time.Time
+	// until delays all requests to the host after a Retry-After response
+	until time.Time
+}
+
+// reserve takes a token and returns how long to wait before sending the request
+func (b *bucket) reserve(now time.Time) time.Duration {
+	if b.limits.Rate < 0 {
+		return max(b.until.Sub(now), 0)
+	}
+
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.limits.Rate, float64(b.limits.Burst))
+	b.last = now
+	b.tokens--
+
+	wait := time.Duration(0)
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.limits.Rate * float64(time.Second))
+	}
+	return max(wait, b.until.Sub(now))
+}
+
+// limitTransport throttles requests per host, caps the requests in flight and retries requests
+// answered with 429 Too Many Requests or 503 Service Unavailable after their Retry-After delay
+type limitTransport struct {
+	base       http.RoundTripper
+	limits     Limits
+	hostLimits map[string]Limits
+	maxRetries int
+	// slots holds a value for every request in flight, nil if the number is not capped
+	slots  chan struct{}
+	logger logging.Logger
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// newLimitTransport wraps base with the limits of opts
+func newLimitTransport(base http.RoundTripper, opts ClientOptions, logger logging.Logger) *limitTransport {
+	t := &limitTransport{
+		base:       base,
+		logger:     logger,
+		limits:     opts.Limits.withDefaults(),
+		hostLimits: make(map[string]Limits, len(opts.HostLimits)),
+		maxRetries: opts.MaxRetries,
+		buckets:    make(map[string]*bucket),
+		now:        time.Now,
+	}
+	for host, limits := range opts.HostLimits {
+		t.hostLimits[host] = limits.withDefaults()
+	}
+	if t.maxRetries == 0 {
+		t.maxRetries = DefaultMaxRetries
+	}
+
+	switch {
+	case opts.MaxConcurrent == 0:
+		t.slots = make(chan struct{}, DefaultMaxConcurrent)
+	case opts.MaxConcurrent > 0:
+		t.slots = make(chan struct{}, opts.MaxConcurrent)
+	}
+	return t
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	// Requests with a body can only be sent again if it can be recreated
+	retryable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		if err := t.acquire(ctx); err != nil {
+			return nil, err
+		}
+		if err := sleep(ctx, t.reserve(req.URL.Host)); err != nil {
+			t.release()
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			t.release()
+			return nil, err
+		}
+
+		delay, ok := retryAfter(resp, t.now())
+		if ok {
+			t.delay(req.URL.Host, delay)
+		}
+		if !ok || !retryable || attempt >= t.maxRetries || delay > MaxRetryWait {
+			// Error responses are usually dropped after reading the status, so their slot is freed
+			// right away rather than when a caller remembers to close the body
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				t.release()
+				return resp, nil
+			}
+			resp.Body = &releaseBody{ReadCloser: resp.Body, release: t.release}
+			return resp, nil
+		}
+
+		t.logger.Printf("    WARNING: %s answered %s, retrying in %s\n", req.URL.Host, resp.Status, delay)
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		t.release()
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// acquire waits for a free slot for a request in flight
+func (t *limitTransport) acquire(ctx context.Context) error {
+	if t.slots == nil {
+		return nil
+	}
+	select {
+	case t.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees the slot of a request in flight
+func (t *limitTransport) release() {
+	if t.slots != nil {
+		<-t.slots
+	}
+}
+
+// reserve takes a token from the bucket of host and returns how long to wait for it
+func (t *limitTransport) reserve(host string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.bucket(host).reserve(t.now())
+}
+
+// delay holds back all requests to host for the given duration
+func (t *limitTransport) delay(host string, delay time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := t.bucket(host)
+	if until := t.now().Add(delay); until.After(b.until) {
+		b.until = until
+	}
+}
+
+// bucket returns the token bucket of host, creating a full one on first use
+func (t *limitTransport) bucket(host string) *bucket {
+	b, ok := t.buckets[host]
+	if !ok {
+		limits, ok := t.hostLimits[host]
+		if !ok {
+			limits = t.limits
+		}
+		b = &bucket{limits: limits, tokens: float64(limits.Burst), last: t.now()}
+		t.buckets[host] = b
+	}
+	return b
+}
+
+// releaseBody frees the slot of a request in flight once its response body is closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// retryAfter returns the delay requested by the Retry-After header of a 429 or 503 response,
+// given in seconds or as an HTTP date
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for the given duration or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
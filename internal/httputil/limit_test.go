@@ -0,0 +1,121 @@
+package httputil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := &bucket{limits: Limits{Rate: 2, Burst: 2}, tokens: 2, last: now}
+
+	for i, expected := range []time.Duration{0, 0, 500 * time.Millisecond, time.Second} {
+		if wait := b.reserve(now); wait != expected {
+			t.Errorf("Request %d: expected to wait %s, got %s", i, expected, wait)
+		}
+	}
+
+	// After a second the bucket has refilled the two requests it was overdrawn by
+	now = now.Add(time.Second)
+	if wait := b.reserve(now); wait != 500*time.Millisecond {
+		t.Errorf("Expected to wait 500ms after refilling, got %s", wait)
+	}
+
+	b.until = now.Add(time.Minute)
+	if wait := b.reserve(now); wait != time.Minute {
+		t.Errorf("Expected a Retry-After delay to hold back requests, got %s", wait)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/busy" && requests.Add(1) == 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case r.URL.Path == "/down":
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, ClientOptions{})
+	resp, err := c.Get(t.Context(), server.URL+"/busy")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || requests.Load() != 2 {
+		t.Errorf("Expected a retry after 429, got status %d after %d requests", resp.StatusCode, requests.Load())
+	}
+
+	// Delays longer than MaxRetryWait fail over instead of waiting
+	start := time.Now()
+	resp, err = c.Get(t.Context(), server.URL+"/down")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || time.Since(start) > 5*time.Second {
+		t.Errorf("Expected the 503 to be returned at once, got status %d after %s", resp.StatusCode, time.Since(start))
+	}
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for old := peak.Load(); current > old && !peak.CompareAndSwap(old, current); old = peak.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, ClientOptions{MaxConcurrent: 2, Limits: Limits{Rate: -1}})
+	done := make(chan error)
+	for range 6 {
+		go func() {
+			resp, err := c.Get(t.Context(), server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			done <- err
+		}()
+	}
+	for range 6 {
+		if err := <-done; err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+	}
+
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 requests in flight, got %d", peak.Load())
+	}
+}
+
+func TestMaxConcurrentUnclosedErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	c := newTestClient(t, ClientOptions{MaxConcurrent: 2, Limits: Limits{Rate: -1}})
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	// Bodies of error responses are never closed, which must not use up the slots
+	for i := range 6 {
+		resp, err := c.Get(ctx, server.URL)
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected status 404, got %d", resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package httputil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Mirrors is an ordered list of base URLs or local directories that serve the same files
+type Mirrors []string
+
+// URLs returns the location of a path relative to the base on every mirror, in order
+func (m Mirrors) URLs(relative string) []string {
+	urls := make([]string, 0, len(m))
+	for _, base := range m {
+		if !strings.HasSuffix(base, "/") {
+			base += "/"
+		}
+		urls = append(urls, base+relative)
+	}
+	return urls
+}
+
+// Get fetches a path relative to the base from the first mirror that serves it with client c.
+// It returns the response and the URL it was fetched from.
+func (m Mirrors) Get(ctx context.Context, c *Client, relative string) (*http.Response, string, error) {
+	return c.GetFirst(ctx, m.URLs(relative))
+}
+
+// GetFirst requests each URL in turn until one responds with status 200 OK, failing over to
+// the next URL on errors and other statuses. It returns the response and the URL it was fetched from.
+// If ctx is cancelled, the remaining URLs are not tried.
+func (c *Client) GetFirst(ctx context.Context, urls []string) (*http.Response, string, error) {
+	if len(urls) == 0 {
+		return nil, "", fmt.Errorf("no URL to fetch")
+	}
+
+	var failures []string
+	for i, rawURL := range urls {
+		resp, err := c.Get(ctx, rawURL)
+		if err == nil && resp.StatusCode == http.StatusOK {
+			return resp, rawURL, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if err == nil {
+				resp.Body.Close()
+			}
+			return nil, "", ctxErr
+		}
+
+		failure := ""
+		if err != nil {
+			failure = err.Error()
+		} else {
+			resp.Body.Close()
+			failure = fmt.Sprintf("status %d", resp.StatusCode)
+		}
+		failures = append(failures, fmt.Sprintf("%s: %s", rawURL, failure))
+
+		if i < len(urls)-1 {
+			c.logger.Printf("    WARNING: %s failed (%s), trying %s\n", rawURL, failure, urls[i+1])
+		}
+	}
+
+	if len(failures) == 1 {
+		return nil, "", fmt.Errorf("%s", failures[0])
+	}
+	return nil, "", fmt.Errorf("all mirrors failed: %s", strings.Join(failures, "; "))
+}
+
+// localPath returns the file system path of a file:// URL or an absolute local path
+func localPath(rawURL string) (string, bool) {
+	if strings.HasPrefix(rawURL, "file://") {
+		parsed, err := url.Parse(rawURL)
+		if err != nil {
+			return "", false
+		}
+		return filepath.FromSlash(parsed.Path), true
+	}
+
+	if filepath.IsAbs(rawURL) {
+		return rawURL, true
+	}
+	return "", false
+}
+
+// getLocal serves a local file like an HTTP server would: files with their contents, directories
+// as an HTML listing in the style of nginx, and missing paths with status 404
+func getLocal(path string) (*http.Response, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return localResponse(http.StatusNotFound, io.NopCloser(strings.NewReader("")), 0), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return localResponse(http.StatusOK, file, info.Size()), nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var listing bytes.Buffer
+	listing.WriteString("<html><body><pre>\n")
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+
+		modified, size := "", ""
+		if entryInfo, err := entry.Info(); err == nil {
+			modified = entryInfo.ModTime().UTC().Format("02-Jan-2006 15:04")
+			size = fmt.Sprint(entryInfo.Size())
+		}
+
+		escaped := html.EscapeString(name)
+		fmt.Fprintf(&listing, "<a href=\"%s\">%s</a>    %s    %s\n", (&url.URL{Path: name}).EscapedPath(), escaped, modified, size)
+	}
+	listing.WriteString("</pre></body></html>\n")
+
+	// Listings are generated, so their length is reported as unknown like a chunked response
+	return localResponse(http.StatusOK, io.NopCloser(&listing), -1), nil
+}
+
+// localResponse wraps a body read from disk in an HTTP response. length is -1 if it is unknown.
+func localResponse(status int, body io.ReadCloser, length int64) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Header:        make(http.Header),
+		Body:          body,
+		ContentLength: length,
+	}
+}
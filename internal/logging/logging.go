@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+)
+
+// Logger receives the messages printed while downloading and updating. *log.Logger implements it.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+// Debugger is a Logger that also receives debug messages. Other loggers drop them.
+type Debugger interface {
+	Logger
+	Debugf(format string, args ...any)
+}
+
+var (
+	// Stdout prints messages to standard output, the default for the command line
+	Stdout Logger = stdout{}
+	// Verbose prints messages and debug messages to standard output, for --verbose
+	Verbose Logger = verbose{}
+	// Discard drops all messages
+	Discard Logger = discard{}
+)
+
+// OrStdout returns l, or Stdout if l is nil
+func OrStdout(l Logger) Logger {
+	if l == nil {
+		return Stdout
+	}
+	return l
+}
+
+// Debugf passes a debug message to l if it is a Debugger
+func Debugf(l Logger, format string, args ...any) {
+	if d, ok := l.(Debugger); ok {
+		d.Debugf(format, args...)
+	}
+}
+
+// Writer returns a writer that passes everything written to it to l, for output of commands
+// and tables
+func Writer(l Logger) io.Writer {
+	return writer{l}
+}
+
+type writer struct {
+	logger Logger
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	w.logger.Printf("%s", p)
+	return len(p), nil
+}
+
+type stdout struct{}
+
+func (stdout) Printf(format string, args ...any) {
+	fmt.Printf(format, args...)
+}
+
+type verbose struct {
+	stdout
+}
+
+func (verbose) Debugf(format string, args ...any) {
+	fmt.Printf(format, args...)
+}
+
+type discard struct{}
+
+func (discard) Printf(string, ...any) {}
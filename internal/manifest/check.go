@@ -0,0 +1,113 @@
+package manifest
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/SKevo18/mediawiki-updater/internal/version"
+)
+
+// Environment describes the installation a component is checked against
+type Environment struct {
+	MediaWiki string // MediaWiki core version
+	PHP       string // PHP version, or empty if unknown
+	// PHPExtensions are the loaded PHP extensions, or nil if unknown
+	PHPExtensions []string
+	// Lookup returns the version of an installed extension or skin, and whether it is installed
+	Lookup func(componentType, name string) (componentVersion string, installed bool)
+}
+
+// Check returns the requirements of the manifest that env does not satisfy.
+// Requirements that cannot be checked, such as PHP constraints without a known PHP version, are skipped.
+func (m *Manifest) Check(env Environment) []string {
+	var problems []string
+
+	if m.Requires.MediaWiki != "" {
+		if problem := checkConstraint("MediaWiki", m.Requires.MediaWiki, env.MediaWiki); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	for _, name := range sortedKeys(m.Requires.Platform) {
+		constraint := m.Requires.Platform[name]
+		switch {
+		case name == "php":
+			if env.PHP == "" {
+				continue
+			}
+			if problem := checkConstraint("PHP", constraint, env.PHP); problem != "" {
+				problems = append(problems, problem)
+			}
+		case strings.HasPrefix(name, "ext-"):
+			if env.PHPExtensions == nil {
+				continue
+			}
+			extension := strings.TrimPrefix(name, "ext-")
+			if !slices.ContainsFunc(env.PHPExtensions, func(loaded string) bool { return strings.EqualFold(loaded, extension) }) {
+				problems = append(problems, fmt.Sprintf("requires PHP extension %s, which is not loaded", extension))
+			}
+		}
+	}
+
+	for _, dependency := range []struct {
+		componentType string
+		requires      map[string]string
+	}{
+		{"extension", m.Requires.Extensions},
+		{"skin", m.Requires.Skins},
+	} {
+		if env.Lookup == nil {
+			break
+		}
+		for _, name := range sortedKeys(dependency.requires) {
+			installedVersion, installed := env.Lookup(dependency.componentType, name)
+			constraint := dependency.requires[name]
+			if !installed {
+				problems = append(problems, fmt.Sprintf("requires %s %s, which is not installed", dependency.componentType, name))
+				continue
+			}
+			if strings.TrimSpace(constraint) == "*" {
+				continue
+			}
+			if problem := checkConstraint(dependency.componentType+" "+name, constraint, installedVersion); problem != "" {
+				problems = append(problems, problem)
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkConstraint returns a problem if actual does not satisfy the constraint of the named requirement
+func checkConstraint(name, constraintValue, actual string) string {
+	constraint, err := version.ParseConstraint(constraintValue)
+	if err != nil {
+		return fmt.Sprintf("has an unsupported %s requirement: %v", name, err)
+	}
+
+	if actual == "" {
+		return fmt.Sprintf("requires %s %s, but its version is unknown", name, constraintValue)
+	}
+
+	parsed, err := version.Parse(actual)
+	if err != nil {
+		return fmt.Sprintf("requires %s %s, but its version %q cannot be compared", name, constraintValue, actual)
+	}
+
+	if !constraint.Check(parsed) {
+		return fmt.Sprintf("requires %s %s (found %s)", name, constraintValue, actual)
+	}
+	return ""
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
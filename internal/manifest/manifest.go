@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Manifest is the part of an extension.json or skin.json file that describes the component
+type Manifest struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	URL      string   `json:"url"`
+	Requires Requires `json:"requires"`
+}
+
+// Requires lists the requirements of a component, as version constraints keyed by name
+type Requires struct {
+	MediaWiki  string            `json:"MediaWiki"`
+	Platform   map[string]string `json:"platform"` // "php" and PHP extensions like "ext-intl"
+	Extensions map[string]string `json:"extensions"`
+	Skins      map[string]string `json:"skins"`
+}
+
+// Read reads the extension.json or skin.json file of the component in dir, depending on
+// componentType. It returns nil and no error if the component has no manifest.
+func Read(dir, componentType string) (*Manifest, error) {
+	filename := componentType + ".json"
+	content, err := os.ReadFile(filepath.Join(dir, filename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", filename, err)
+	}
+	return &manifest, nil
+}
+
+// ParseComposer reads the PHP version and PHP extension requirements from a composer.json file
+// into a manifest with the given name
+func ParseComposer(name string, data []byte) (*Manifest, error) {
+	var composer struct {
+		Require map[string]string `json:"require"`
+	}
+	if err := json.Unmarshal(data, &composer); err != nil {
+		return nil, fmt.Errorf("invalid composer.json: %w", err)
+	}
+
+	manifest := &Manifest{Name: name, Requires: Requires{Platform: make(map[string]string)}}
+	for key, constraint := range composer.Require {
+		if key == "php" || strings.HasPrefix(key, "ext-") {
+			manifest.Requires.Platform[key] = constraint
+		}
+	}
+	return manifest, nil
+}
+
+// Dependency is an extension or skin required by a component
+type Dependency struct {
+	Type       string // "extension" or "skin"
+	Name       string
+	Constraint string
+}
+
+// Dependencies returns the required extensions and skins, extensions first, each sorted by name
+func (m *Manifest) Dependencies() []Dependency {
+	var dependencies []Dependency
+	for _, name := range sortedKeys(m.Requires.Extensions) {
+		dependencies = append(dependencies, Dependency{"extension", name, m.Requires.Extensions[name]})
+	}
+	for _, name := range sortedKeys(m.Requires.Skins) {
+		dependencies = append(dependencies, Dependency{"skin", name, m.Requires.Skins[name]})
+	}
+	return dependencies
+}
@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	content := `{
+	"name": "Math",
+	"version": "3.0.0",
+	"requires": {
+		"MediaWiki": ">= 1.43",
+		"platform": {"php": ">= 8.1", "ext-intl": "*"},
+		"extensions": {"Cite": "*"}
+	},
+	"AutoloadNamespaces": {"MediaWiki\\Extension\\Math\\": "src/"}
+}`
+	if err := os.WriteFile(filepath.Join(dir, "extension.json"), []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to create extension.json: %v", err)
+	}
+
+	manifest, err := Read(dir, "extension")
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if manifest.Name != "Math" || manifest.Version != "3.0.0" || manifest.Requires.MediaWiki != ">= 1.43" ||
+		manifest.Requires.Platform["ext-intl"] != "*" || manifest.Requires.Extensions["Cite"] != "*" {
+		t.Errorf("Manifest not parsed correctly: %+v", manifest)
+	}
+
+	if manifest, err := Read(dir, "skin"); manifest != nil || err != nil {
+		t.Errorf("Expected no manifest and no error for a missing skin.json, got %+v, %v", manifest, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	manifest := &Manifest{
+		Name: "Example",
+		Requires: Requires{
+			MediaWiki:  ">= 1.44",
+			Platform:   map[string]string{"php": ">= 8.1", "ext-intl": "*", "ext-mbstring": "*"},
+			Extensions: map[string]string{"Cite": "*", "Echo": ">= 2.0", "Missing": "*"},
+			Skins:      map[string]string{"Vector": ">= 1.0"},
+		},
+	}
+
+	installed := map[string]string{"extension/Cite": "", "extension/Echo": "1.5.0", "skin/Vector": "1.0.0"}
+	env := Environment{
+		MediaWiki:     "1.43.1",
+		PHP:           "7.4.33",
+		PHPExtensions: []string{"Core", "mbstring"},
+		Lookup: func(componentType, name string) (string, bool) {
+			componentVersion, exists := installed[componentType+"/"+name]
+			return componentVersion, exists
+		},
+	}
+
+	expected := []string{
+		"requires MediaWiki >= 1.44 (found 1.43.1)",
+		"requires PHP extension intl, which is not loaded",
+		"requires PHP >= 8.1 (found 7.4.33)",
+		"requires extension Echo >= 2.0 (found 1.5.0)",
+		"requires extension Missing, which is not installed",
+	}
+
+	problems := manifest.Check(env)
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected problems:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(problems, "\n"))
+	}
+
+	// PHP requirements are skipped if the PHP version and extensions are unknown
+	env.MediaWiki, env.PHP, env.PHPExtensions = "1.44.0-alpha", "", nil
+	installed["extension/Echo"] = "2.1.0"
+	installed["extension/Missing"] = ""
+	if problems := manifest.Check(env); len(problems) > 0 {
+		t.Errorf("Expected no problems, got %v", problems)
+	}
+}
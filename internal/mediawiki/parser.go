@@ -1,9 +1,10 @@
 package mediawiki
 
 import (
+	"context"
 	"fmt"
-	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
@@ -15,33 +16,51 @@ const (
 )
 
 // Parser handles MediaWiki release page parsing
-type Parser struct{}
+type Parser struct {
+	releases httputil.Mirrors
+	composer httputil.Mirrors
+	http     *httputil.Client
+}
+
+// Options contains configuration options for the parser
+type Options struct {
+	// Releases are mirrors of BaseDownloadURL, tried in order; BaseDownloadURL if empty
+	Releases []string
+	// Composer are mirrors of ComposerBaseURL, tried in order; ComposerBaseURL if empty
+	Composer []string
+	// HTTPClient sends the requests; nil uses a client with the default settings
+	HTTPClient *httputil.Client
+}
 
 // NewParser creates a new Parser instance
-func NewParser() *Parser {
-	return &Parser{}
+func NewParser(opts Options) *Parser {
+	p := &Parser{releases: opts.Releases, composer: opts.Composer, http: opts.HTTPClient}
+	if len(p.releases) == 0 {
+		p.releases = httputil.Mirrors{BaseDownloadURL}
+	}
+	if len(p.composer) == 0 {
+		p.composer = httputil.Mirrors{ComposerBaseURL}
+	}
+	if p.http == nil {
+		p.http = httputil.DefaultClient()
+	}
+	return p
 }
 
 // GetDownloadURL parses the MediaWiki release page to find the download URL for a specific version
-func (p *Parser) GetDownloadURL(version string) (string, error) {
+func (p *Parser) GetDownloadURL(ctx context.Context, version string) (string, error) {
 	// Extract major.minor version for URL construction (e.g., "1.43.1" -> "1.43")
 	majorMinor, err := p.extractMajorMinor(version)
 	if err != nil {
 		return "", err
 	}
 
-	releasePageURL := fmt.Sprintf("%s%s/", BaseDownloadURL, majorMinor)
-
-	resp, err := httputil.Get(releasePageURL)
+	resp, releasePageURL, err := p.releases.Get(ctx, p.http, majorMinor+"/")
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch release page: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("release page returned status %d", resp.StatusCode)
-	}
-
 	doc, err := goquery.NewDocumentFromReader(resp.Body)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse release page: %w", err)
@@ -65,6 +84,35 @@ func (p *Parser) GetDownloadURL(version string) (string, error) {
 	return downloadURL, nil
 }
 
+// ListVersionSeries returns the major.minor versions listed on the release page, e.g. "1.43"
+func (p *Parser) ListVersionSeries(ctx context.Context) ([]string, error) {
+	resp, _, err := p.releases.Get(ctx, p.http, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch releases page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse releases page: %w", err)
+	}
+
+	var versions []string
+	doc.Find("a").Each(func(i int, s *goquery.Selection) {
+		href := s.AttrOr("href", "")
+		// Look for version directories (e.g., "1.43/")
+		if strings.Contains(href, ".") && strings.HasSuffix(href, "/") {
+			version := strings.TrimSuffix(href, "/")
+			if strings.Count(version, ".") == 1 { // Only major.minor versions
+				versions = append(versions, version)
+			}
+		}
+	})
+
+	sort.Strings(versions)
+	return versions, nil
+}
+
 // extractMajorMinor extracts the major.minor version from a full version string
 func (p *Parser) extractMajorMinor(version string) (string, error) {
 	// Match pattern like "1.43.1" and extract "1.43"
@@ -75,3 +123,19 @@ func (p *Parser) extractMajorMinor(version string) (string, error) {
 	}
 	return matches[1], nil
 }
+
+// VersionTag converts a MediaWiki version to the branch name used by ExtDist (e.g., "1.43.1" -> "REL1_43")
+func VersionTag(version string) (string, error) {
+	if version == "" {
+		return "", fmt.Errorf("MediaWiki version not specified in config")
+	}
+
+	// Extract major.minor version and convert to REL format
+	re := regexp.MustCompile(`^(\d+)\.(\d+)(\.\d+.*)?$`)
+	matches := re.FindStringSubmatch(version)
+	if len(matches) < 3 {
+		return "", fmt.Errorf("invalid version format: %s", version)
+	}
+
+	return fmt.Sprintf("REL%s_%s", matches[1], matches[2]), nil
+}
@@ -1,11 +1,15 @@
 package mediawiki
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
 func TestExtractMajorMinor(t *testing.T) {
-	parser := NewParser()
+	parser := NewParser(Options{})
 
 	tests := []struct {
 		input    string
@@ -39,12 +43,87 @@ func TestExtractMajorMinor(t *testing.T) {
 	}
 }
 
+func TestVersionTag(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		hasError bool
+	}{
+		{"1.43.1", "REL1_43", false},
+		{"1.44.0-rc.0", "REL1_44", false},
+		{"1.39", "REL1_39", false},
+		{"invalid", "", true},
+		{"", "", true},
+	}
+
+	for _, test := range tests {
+		result, err := VersionTag(test.input)
+
+		if test.hasError {
+			if err == nil {
+				t.Errorf("Expected error for input %s, but got none", test.input)
+			}
+		} else if err != nil || result != test.expected {
+			t.Errorf("Expected %s for input %s, got %s (error: %v)", test.expected, test.input, result, err)
+		}
+	}
+}
+
 func TestNewParser(t *testing.T) {
-	parser := NewParser()
+	parser := NewParser(Options{})
 	if parser == nil {
 		t.Error("NewParser returned nil")
 	}
 }
 
-// Note: GetDownloadURL requires network access, so we don't test it in unit tests
-// It would require mocking the HTTP client or using integration tests
+func TestGetDownloadURLMirrors(t *testing.T) {
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	mirror := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(mirror, "1.43"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mirror, "1.43", "mediawiki-1.43.1.tar.gz"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewParser(Options{Releases: []string{broken.URL, mirror}})
+
+	url, err := parser.GetDownloadURL(t.Context(), "1.43.1")
+	if err != nil {
+		t.Fatalf("Expected the local mirror to serve the release, got %v", err)
+	}
+	if expected := mirror + "/1.43/mediawiki-1.43.1.tar.gz"; url != expected {
+		t.Errorf("Expected %s, got %s", expected, url)
+	}
+
+	versions, err := parser.ListVersionSeries(t.Context())
+	if err != nil || len(versions) != 1 || versions[0] != "1.43" {
+		t.Errorf("Expected version series 1.43, got %v, %v", versions, err)
+	}
+}
+
+func TestMinimumPHP(t *testing.T) {
+	tests := []struct {
+		version string
+		php     string
+		found   bool
+	}{
+		{"1.43.1", "8.1.0", true},
+		{"1.44.0-rc.0", "8.1.0", true},
+		{"1.39.10", "7.4.3", true},
+		{"1.35.0", "7.3.19", true},
+		{"1.27.0", "", false},
+		{"invalid", "", false},
+	}
+
+	for _, test := range tests {
+		php, found := MinimumPHP(test.version)
+		if php != test.php || found != test.found {
+			t.Errorf("MinimumPHP(%s) = %s, %v, expected %s, %v", test.version, php, found, test.php, test.found)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package mediawiki
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/SKevo18/mediawiki-updater/internal/version"
+)
+
+// ComposerBaseURL serves composer.json of every MediaWiki release tag at <version>/composer.json
+const ComposerBaseURL = "https://raw.githubusercontent.com/wikimedia/mediawiki/"
+
+// phpMinimums are the lowest PHP versions supported by MediaWiki release branches, in
+// ascending order. They are used if composer.json of a release cannot be fetched.
+var phpMinimums = []struct {
+	mediawiki string
+	php       string
+}{
+	{"1.31", "7.0.13"},
+	{"1.34", "7.2.9"},
+	{"1.35", "7.3.19"},
+	{"1.39", "7.4.3"},
+	{"1.42", "8.0.0"},
+	{"1.43", "8.1.0"},
+}
+
+// FetchComposerJSON downloads composer.json of a MediaWiki release, which declares the PHP
+// version and PHP extensions the release requires
+func (p *Parser) FetchComposerJSON(ctx context.Context, mwVersion string) ([]byte, error) {
+	resp, _, err := p.composer.Get(ctx, p.http, mwVersion+"/composer.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch composer.json: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read composer.json: %w", err)
+	}
+	return data, nil
+}
+
+// MinimumPHP returns the lowest PHP version supported by a MediaWiki release from a built-in
+// table, or false if the release is older than the table
+func MinimumPHP(mwVersion string) (string, bool) {
+	parsed, err := version.Parse(mwVersion)
+	if err != nil {
+		return "", false
+	}
+
+	minimum := ""
+	for _, entry := range phpMinimums {
+		if parsed.Release().Compare(version.MustParse(entry.mediawiki)) >= 0 {
+			minimum = entry.php
+		}
+	}
+	return minimum, minimum != ""
+}
@@ -0,0 +1,85 @@
+package php
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// DefaultBinary is the PHP binary that is used if none is configured
+const DefaultBinary = "php"
+
+const (
+	// versionScript prints the PHP version
+	versionScript = `echo PHP_VERSION;`
+	// extensionsScript prints the loaded extensions, separated by commas
+	extensionsScript = `echo implode(",", get_loaded_extensions());`
+)
+
+// versionPattern matches a PHP version like 8.2.7 or 8.4.0RC1
+var versionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+\S*$`)
+
+// Info describes a PHP runtime
+type Info struct {
+	Binary     string
+	Version    string
+	Extensions []string // loaded extensions as reported by get_loaded_extensions(), e.g. "intl"
+}
+
+// Detect runs the PHP binary to read its version and loaded extensions. The binary is killed if
+// ctx is cancelled.
+func Detect(ctx context.Context, binary string) (*Info, error) {
+	if binary == "" {
+		binary = DefaultBinary
+	}
+
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("PHP binary %s not found: %w", binary, err)
+	}
+
+	version, err := run(ctx, path, versionScript)
+	if err != nil {
+		return nil, err
+	}
+	if !versionPattern.MatchString(version) {
+		return nil, fmt.Errorf("%s printed %q instead of its version", binary, version)
+	}
+
+	extensions, err := run(ctx, path, extensionsScript)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{Binary: path, Version: version}
+	for _, extension := range strings.Split(extensions, ",") {
+		if extension = strings.TrimSpace(extension); extension != "" {
+			info.Extensions = append(info.Extensions, extension)
+		}
+	}
+	return info, nil
+}
+
+// run runs a PHP script and returns what the script printed. The scripts print no newline, so
+// startup warnings and deprecations that PHP prints before them are skipped.
+func run(ctx context.Context, path, script string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "-r", script)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("failed to run %s: %w", path, ctx.Err())
+		}
+		return "", fmt.Errorf("failed to run %s: %w: %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	output := strings.TrimRight(stdout.String(), "\r\n")
+	if i := strings.LastIndexAny(output, "\r\n"); i >= 0 {
+		output = output[i+1:]
+	}
+	return strings.TrimSpace(output), nil
+}
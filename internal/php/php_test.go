@@ -0,0 +1,87 @@
+package php
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePHP creates a shell script that prints output like php -r would for the version and
+// extensions scripts
+func fakePHP(t *testing.T, version, extensions string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake PHP binary requires a POSIX shell")
+	}
+
+	binary := filepath.Join(t.TempDir(), "php")
+	script := "#!/bin/sh\ncase \"$2\" in\n*PHP_VERSION*) printf '" + version + "' ;;\n*) printf '" + extensions + "' ;;\nesac\n"
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatalf("Failed to create fake PHP binary: %v", err)
+	}
+	return binary
+}
+
+func TestDetect(t *testing.T) {
+	binary := fakePHP(t, `8.2.7`, `Core,intl,mbstring,Zend OPcache`)
+
+	info, err := Detect(t.Context(), binary)
+	if err != nil {
+		t.Fatalf("Failed to detect PHP: %v", err)
+	}
+
+	if info.Version != "8.2.7" || strings.Join(info.Extensions, ",") != "Core,intl,mbstring,Zend OPcache" {
+		t.Errorf("Unexpected PHP info: %+v", info)
+	}
+}
+
+func TestDetectSkipsStartupWarnings(t *testing.T) {
+	warning := `PHP Deprecated:  Directive allow_url_include is deprecated in Unknown on line 0\n`
+	binary := fakePHP(t, warning+`8.3.1`, warning+`Core,intl`)
+
+	info, err := Detect(t.Context(), binary)
+	if err != nil {
+		t.Fatalf("Failed to detect PHP: %v", err)
+	}
+
+	if info.Version != "8.3.1" || strings.Join(info.Extensions, ",") != "Core,intl" {
+		t.Errorf("Unexpected PHP info: %+v", info)
+	}
+}
+
+func TestDetectInvalidVersion(t *testing.T) {
+	if _, err := Detect(t.Context(), fakePHP(t, `Fatal error`, ``)); err == nil {
+		t.Error("Expected error for output that is not a version")
+	}
+}
+
+func TestDetectCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake PHP binary requires a POSIX shell")
+	}
+	binary := filepath.Join(t.TempDir(), "php")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\nexec sleep 10\n"), 0o755); err != nil {
+		t.Fatalf("Failed to create fake PHP binary: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := Detect(ctx, binary); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to stop PHP, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected a hung PHP binary to be killed, took %s", elapsed)
+	}
+}
+
+func TestDetectMissingBinary(t *testing.T) {
+	if _, err := Detect(t.Context(), filepath.Join(t.TempDir(), "php")); err == nil {
+		t.Error("Expected error for a missing PHP binary")
+	}
+}
@@ -0,0 +1,75 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// barWidth is the number of characters of a progress bar
+const barWidth = 30
+
+// redrawInterval limits how often a progress bar is redrawn
+const redrawInterval = 100 * time.Millisecond
+
+// Bar draws a progress bar for every download on a terminal, redrawing the current line
+type Bar struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+// NewBar creates a reporter that draws progress bars to w
+func NewBar(w io.Writer) *Bar {
+	return &Bar{w: w, now: time.Now}
+}
+
+// Start implements Reporter
+func (b *Bar) Start(name string, size int64) Tracker {
+	return &barTracker{bar: b, state: state{name: name, size: size, start: b.now()}}
+}
+
+type barTracker struct {
+	bar   *Bar
+	state state
+	drawn time.Time
+}
+
+func (t *barTracker) Progress(done int64) {
+	t.bar.mu.Lock()
+	defer t.bar.mu.Unlock()
+
+	t.state.done = done
+	if now := t.bar.now(); now.Sub(t.drawn) >= redrawInterval {
+		t.drawn = now
+		t.draw(now, "")
+	}
+}
+
+func (t *barTracker) Done(err error) {
+	t.bar.mu.Lock()
+	defer t.bar.mu.Unlock()
+
+	if err != nil {
+		t.draw(t.bar.now(), "failed\n")
+		return
+	}
+	elapsed := t.bar.now().Sub(t.state.start).Round(100 * time.Millisecond)
+	t.state.size = t.state.done
+	t.draw(t.bar.now(), fmt.Sprintf("done in %s\n", elapsed))
+}
+
+// draw replaces the current line with the bar, followed by suffix or the progress summary
+func (t *barTracker) draw(now time.Time, suffix string) {
+	bar := strings.Repeat("?", barWidth)
+	if percent := t.state.percent(); percent >= 0 {
+		filled := percent * barWidth / 100
+		bar = strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+	}
+	if suffix == "" {
+		suffix = t.state.summary(now)
+	}
+	fmt.Fprintf(t.bar.w, "\r\033[K    %s [%s] %s", t.state.name, bar, suffix)
+}
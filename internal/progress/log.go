@@ -0,0 +1,66 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Log prints a line with the percentage of every download at most once per interval, for logs
+// of non-interactive runs
+type Log struct {
+	mu       sync.Mutex
+	w        io.Writer
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewLog creates a reporter that prints progress lines to w
+func NewLog(w io.Writer, interval time.Duration) *Log {
+	return &Log{w: w, interval: interval, now: time.Now}
+}
+
+// Start implements Reporter
+func (l *Log) Start(name string, size int64) Tracker {
+	now := l.now()
+	return &logTracker{log: l, state: state{name: name, size: size, start: now}, printed: now}
+}
+
+type logTracker struct {
+	log     *Log
+	state   state
+	printed time.Time
+}
+
+func (t *logTracker) Progress(done int64) {
+	t.log.mu.Lock()
+	defer t.log.mu.Unlock()
+
+	t.state.done = done
+	if now := t.log.now(); now.Sub(t.printed) >= t.log.interval {
+		t.printed = now
+		t.print(now)
+	}
+}
+
+func (t *logTracker) Done(err error) {
+	t.log.mu.Lock()
+	defer t.log.mu.Unlock()
+
+	if err != nil {
+		fmt.Fprintf(t.log.w, "    %s: failed after %s\n", t.state.name, formatBytes(t.state.done))
+		return
+	}
+	elapsed := t.log.now().Sub(t.state.start).Round(100 * time.Millisecond)
+	fmt.Fprintf(t.log.w, "    %s: %s in %s\n", t.state.name, formatBytes(t.state.done), elapsed)
+}
+
+// print writes a line like "mediawiki-1.43.1.tar.gz: 25% (15.0 MiB of 60.0 MiB, ETA 12s)"
+func (t *logTracker) print(now time.Time) {
+	if percent := t.state.percent(); percent >= 0 {
+		fmt.Fprintf(t.log.w, "    %s: %d%% (%s)\n", t.state.name, percent, t.state.summary(now))
+		return
+	}
+	fmt.Fprintf(t.log.w, "    %s: %s\n", t.state.name, t.state.summary(now))
+}
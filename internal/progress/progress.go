@@ -0,0 +1,118 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Reporter is notified of downloads as they progress. Start may be called from several
+// goroutines at once.
+type Reporter interface {
+	// Start is called when the download of name begins and returns the tracker that receives its
+	// progress. size is the length of the download in bytes, or -1 if it is unknown.
+	Start(name string, size int64) Tracker
+}
+
+// Tracker receives the progress of a single download
+type Tracker interface {
+	// Progress is called with the number of bytes downloaded so far
+	Progress(done int64)
+	// Done is called once the download has ended, with the error that ended it if it failed
+	Done(err error)
+}
+
+// Nop is a Reporter that ignores all downloads
+var Nop Reporter = nopReporter{}
+
+type nopReporter struct{}
+
+func (nopReporter) Start(string, int64) Tracker { return nopTracker{} }
+
+type nopTracker struct{}
+
+func (nopTracker) Progress(int64) {}
+func (nopTracker) Done(error)     {}
+
+// Auto returns a reporter that draws progress bars if f is a terminal, and prints a line every
+// interval otherwise
+func Auto(f *os.File, interval time.Duration) Reporter {
+	if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return NewBar(f)
+	}
+	return NewLog(f, interval)
+}
+
+// Reader returns a reader that reports the bytes read from r to t
+func Reader(r io.Reader, t Tracker) io.Reader {
+	return &reader{r: r, tracker: t}
+}
+
+type reader struct {
+	r       io.Reader
+	tracker Tracker
+	done    int64
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.done += int64(n)
+		r.tracker.Progress(r.done)
+	}
+	return n, err
+}
+
+// state is the progress of a download shared by the reporters of this package
+type state struct {
+	name  string
+	size  int64
+	start time.Time
+	done  int64
+}
+
+// percent returns the downloaded share in percent, or -1 if the size is unknown
+func (s *state) percent() int {
+	if s.size <= 0 {
+		return -1
+	}
+	return int(min(s.done*100/s.size, 100))
+}
+
+// eta estimates the time left from the average speed so far, or returns -1 if it is unknown
+func (s *state) eta(now time.Time) time.Duration {
+	elapsed := now.Sub(s.start)
+	if s.size <= 0 || s.done <= 0 || elapsed <= 0 {
+		return -1
+	}
+	left := float64(s.size-s.done) / float64(s.done) * float64(elapsed)
+	return time.Duration(left).Round(time.Second)
+}
+
+// summary describes the progress like "15.0 MiB of 60.0 MiB, ETA 12s"
+func (s *state) summary(now time.Time) string {
+	if s.size <= 0 {
+		return formatBytes(s.done)
+	}
+	text := fmt.Sprintf("%s of %s", formatBytes(s.done), formatBytes(s.size))
+	if eta := s.eta(now); eta >= 0 && s.done < s.size {
+		text += fmt.Sprintf(", ETA %s", eta)
+	}
+	return text
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. 1.5 MiB
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	value, exponent := float64(bytes)/unit, 0
+	for value >= unit && exponent < 4 {
+		value /= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[exponent])
+}
@@ -0,0 +1,99 @@
+package progress
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clock is a fake time source advanced by the tests
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func TestLog(t *testing.T) {
+	var out strings.Builder
+	c := &clock{now: time.Unix(0, 0)}
+	log := NewLog(&out, 5*time.Second)
+	log.now = c.Now
+
+	tracker := log.Start("mediawiki-1.43.1.tar.gz", 60<<20)
+	c.now = c.now.Add(time.Second)
+	tracker.Progress(1 << 20) // before the interval, not printed
+	c.now = c.now.Add(4 * time.Second)
+	tracker.Progress(15 << 20)
+	c.now = c.now.Add(time.Second)
+	tracker.Done(nil)
+
+	expected := "    mediawiki-1.43.1.tar.gz: 25% (15.0 MiB of 60.0 MiB, ETA 15s)\n" +
+		"    mediawiki-1.43.1.tar.gz: 15.0 MiB in 6s\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected log output:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+
+	out.Reset()
+	tracker = log.Start("Math.tar.gz", -1)
+	c.now = c.now.Add(5 * time.Second)
+	tracker.Progress(2048)
+	tracker.Done(errors.New("connection reset"))
+	expected = "    Math.tar.gz: 2.0 KiB\n    Math.tar.gz: failed after 2.0 KiB\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected log output for an unknown size:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestBar(t *testing.T) {
+	var out strings.Builder
+	c := &clock{now: time.Unix(0, 0)}
+	bar := NewBar(&out)
+	bar.now = c.Now
+
+	tracker := bar.Start("VisualEditor.tar.gz", 2048)
+	c.now = c.now.Add(time.Second)
+	tracker.Progress(1024)
+	tracker.Progress(1536) // within the redraw interval, not drawn
+	tracker.Done(nil)
+
+	lines := strings.Split(out.String(), "\r\033[K")
+	if len(lines) != 3 {
+		t.Fatalf("Expected two redraws, got %q", out.String())
+	}
+	if !strings.Contains(lines[1], "[===============               ] 1.0 KiB of 2.0 KiB, ETA 1s") {
+		t.Errorf("Unexpected bar: %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "["+strings.Repeat("=", barWidth)+"] done in 1s\n") {
+		t.Errorf("Unexpected final bar: %q", lines[2])
+	}
+}
+
+type recorder struct{ updates []int64 }
+
+func (r *recorder) Progress(done int64) { r.updates = append(r.updates, done) }
+func (r *recorder) Done(error)          {}
+
+func TestReader(t *testing.T) {
+	tracker := &recorder{}
+	data, err := io.ReadAll(io.LimitReader(Reader(strings.NewReader(strings.Repeat("x", 10)), tracker), 10))
+	if err != nil || len(data) != 10 {
+		t.Fatalf("Unexpected read: %d bytes, %v", len(data), err)
+	}
+	if len(tracker.updates) == 0 || tracker.updates[len(tracker.updates)-1] != 10 {
+		t.Errorf("Expected the tracker to end at 10 bytes, got %v", tracker.updates)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, expected := range map[int64]string{
+		0:        "0 B",
+		1023:     "1023 B",
+		1536:     "1.5 KiB",
+		60 << 20: "60.0 MiB",
+		5 << 29:  "2.5 GiB",
+	} {
+		if got := formatBytes(n); got != expected {
+			t.Errorf("formatBytes(%d) = %s, expected %s", n, got, expected)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/SKevo18/mediawiki-updater/internal/bundle"
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/downloader"
+)
+
+// Mirror downloads MediaWiki core and every configured extension and skin (and their dependencies,
+// if enabled) into an offline bundle in outDir. Git components are archived to tarballs, and
+// the manifest records the SHA-256 checksum of every file. Update installs from the bundle
+// with the Bundle option. Cancelling ctx stops the download.
+func (u *Updater) Mirror(ctx context.Context, outDir string) error {
+	u.reset()
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+
+	tempDir, err := os.MkdirTemp("", "mediawiki-temp-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	core, err := u.mirrorMediaWikiCore(ctx, tempDir, outDir)
+	if err != nil {
+		return fmt.Errorf("failed to download MediaWiki core: %w", err)
+	}
+
+	// The bundle may be installed anywhere, so it contains every dependency
+	if err := u.downloadComponents(ctx, tempDir, ""); err != nil {
+		return err
+	}
+
+	manifest := &bundle.Manifest{Created: time.Now().UTC(), MediaWiki: *core}
+	u.logger.Printf("Packing %d extensions and skins...\n", len(u.staged))
+	for _, staged := range u.staged {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		entry := u.report.Components[staged.report]
+		dir := filepath.ToSlash(staged.dir)
+
+		artifact, err := bundle.Pack(filepath.Join(tempDir, staged.dir), outDir, dir+".tar.gz")
+		if err != nil {
+			return err
+		}
+
+		manifest.Components = append(manifest.Components, bundle.Component{
+			Type:             entry.Type,
+			Name:             entry.Name,
+			Distributor:      entry.Distributor,
+			Dir:              dir,
+			Version:          entry.Version,
+			RequestedVersion: entry.RequestedVersion,
+			Commit:           entry.Commit,
+			URL:              entry.URL,
+			RequiredBy:       entry.RequiredBy,
+			Artifact:         artifact,
+		})
+	}
+
+	if err := manifest.Write(outDir); err != nil {
+		return err
+	}
+	u.logger.Printf("Wrote bundle to %s\n", outDir)
+	return nil
+}
+
+// mirrorMediaWikiCore downloads the MediaWiki release archive into the bundle and unpacks it into tempDir
+func (u *Updater) mirrorMediaWikiCore(ctx context.Context, tempDir, outDir string) (*bundle.Core, error) {
+	version := u.config.MediaWiki.Version
+	if version == "" {
+		return nil, fmt.Errorf("MediaWiki version not specified in config")
+	}
+
+	u.logger.Printf("Downloading MediaWiki core version %s...\n", version)
+
+	downloadURL, err := u.mwParser.GetDownloadURL(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	file := path.Base(downloadURL)
+	if err := u.downloader.DownloadFile(ctx, downloadURL, filepath.Join(outDir, file)); err != nil {
+		return nil, err
+	}
+
+	artifact, err := bundle.NewArtifact(outDir, file)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.unpack(ctx, outDir, artifact, tempDir); err != nil {
+		return nil, err
+	}
+
+	return &bundle.Core{Version: version, URL: downloadURL, Artifact: artifact}, nil
+}
+
+// readBundle reads the manifest of a bundle and checks that it was built for the configured MediaWiki version
+func readBundle(dir string, cfg *config.Config) (*bundle.Manifest, error) {
+	manifest, err := bundle.Read(dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.MediaWiki.Version != cfg.MediaWiki.Version {
+		return nil, fmt.Errorf("bundle %s contains MediaWiki %s, but the configuration requires %s", dir, manifest.MediaWiki.Version, cfg.MediaWiki.Version)
+	}
+	return manifest, nil
+}
+
+// installBundle unpacks MediaWiki core and the configured extensions and skins from the bundle
+// into tempDir, verifying their checksums. Dependencies that were added when the bundle was built
+// are unpacked too. Nothing is downloaded.
+func (u *Updater) installBundle(ctx context.Context, tempDir string) error {
+	u.logger.Printf("Unpacking MediaWiki core version %s from %s...\n", u.bundle.MediaWiki.Version, u.bundleDir)
+	if err := u.unpack(ctx, u.bundleDir, u.bundle.MediaWiki.Artifact, tempDir); err != nil {
+		return fmt.Errorf("failed to unpack MediaWiki core: %w", err)
+	}
+
+	components := slices.Concat(u.config.Extensions, u.config.Skins)
+	if len(components) > 0 {
+		u.logger.Printf("Unpacking %d extensions and skins...\n", len(components))
+	}
+
+	configured := make(map[string]bool)
+	for _, component := range components {
+		configured[componentKey(component.Type, component.Name)] = true
+		if err := u.installBundledComponent(ctx, tempDir, component, u.bundle.Component(component.Type, component.Name)); err != nil {
+			return err
+		}
+	}
+
+	for i := range u.bundle.Components {
+		bundled := &u.bundle.Components[i]
+		if len(bundled.RequiredBy) == 0 || configured[componentKey(bundled.Type, bundled.Name)] {
+			continue
+		}
+
+		component := config.ComponentConfig{
+			Type:        bundled.Type,
+			Name:        bundled.Name,
+			Distributor: bundled.Distributor,
+			Version:     bundled.Version,
+		}
+		if err := u.installBundledComponent(ctx, tempDir, component, bundled); err != nil {
+			return err
+		}
+		u.report.Components[len(u.report.Components)-1].RequiredBy = bundled.RequiredBy
+	}
+
+	return nil
+}
+
+// installBundledComponent unpacks an extension or skin from the bundle and stages it.
+// A component that is missing from the bundle is recorded like a failed download.
+func (u *Updater) installBundledComponent(ctx context.Context, tempDir string, component config.ComponentConfig, bundled *bundle.Component) error {
+	u.logger.Printf("  - %s (from bundle)\n", component.Name)
+	if bundled == nil {
+		return u.record(tempDir, component, nil, fmt.Errorf("not in bundle %s", u.bundleDir))
+	}
+	if !filepath.IsLocal(filepath.FromSlash(bundled.Dir)) {
+		return u.record(tempDir, component, nil, fmt.Errorf("invalid bundle directory %q", bundled.Dir))
+	}
+
+	dir := filepath.Join(tempDir, filepath.FromSlash(bundled.Dir))
+	err := downloader.FetchInto(dir, func(dir string) error {
+		return u.unpack(ctx, u.bundleDir, bundled.Artifact, dir)
+	})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return u.record(tempDir, component, nil, err)
+	}
+
+	return u.record(tempDir, component, &downloader.Result{
+		Dir:       dir,
+		Version:   bundled.Version,
+		Commit:    bundled.Commit,
+		URL:       bundled.URL,
+		Requested: bundled.RequestedVersion,
+	}, nil)
+}
+
+// unpack verifies the checksum of a bundle artifact and extracts it into targetDir without its top-level directory
+func (u *Updater) unpack(ctx context.Context, bundleDir string, artifact bundle.Artifact, targetDir string) error {
+	file, err := artifact.Open(bundleDir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return u.extractor.ExtractStripped(ctx, file, targetDir)
+}
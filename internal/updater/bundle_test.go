@@ -0,0 +1,147 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SKevo18/mediawiki-updater/internal/bundle"
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/downloader"
+	"github.com/SKevo18/mediawiki-updater/internal/extractor"
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+	"github.com/SKevo18/mediawiki-updater/internal/mediawiki"
+)
+
+// writeArchive packs a directory with a single file into a tarball at dir/file
+func writeArchive(t *testing.T, topDir, name, dir, file string) {
+	t.Helper()
+
+	src := filepath.Join(t.TempDir(), topDir)
+	if err := os.MkdirAll(src, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, name), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bundle.Pack(src, dir, file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestDownloader creates a Downloader for a test that discards its messages
+func newTestDownloader(t *testing.T, opts downloader.Options) *downloader.Downloader {
+	t.Helper()
+	opts.Logger = logging.Discard
+	d, err := downloader.NewDownloader(opts)
+	if err != nil {
+		t.Fatalf("Failed to create downloader: %v", err)
+	}
+	return d
+}
+
+func TestMirrorAndInstallBundle(t *testing.T) {
+	// Local mirrors of the release and ExtDist directories
+	releases, extdist := t.TempDir(), t.TempDir()
+	writeArchive(t, "mediawiki-1.43.1", "composer.json", releases, "1.43/mediawiki-1.43.1.tar.gz")
+	writeArchive(t, "Math", "extension.json", extdist, "extensions/Math-REL1_43-6ef1a2b.tar.gz")
+
+	cfg := &config.Config{
+		MediaWiki:  config.MediaWikiConfig{Version: "1.43.1"},
+		Extensions: []config.ComponentConfig{{Type: config.TypeExtension, Distributor: "extdist", Name: "Math"}},
+	}
+	sources := config.SourcesConfig{Releases: []string{releases}, ExtDist: []string{extdist}, ExtDistAPI: []string{config.NoSource}}
+
+	mirror := &Updater{
+		logger:     logging.Discard,
+		config:     cfg,
+		downloader: newTestDownloader(t, DownloaderOptions(sources, false, nil)),
+		extractor:  extractor.NewExtractor(),
+		mwParser:   mediawiki.NewParser(ParserOptions(sources, nil)),
+		report:     &Report{},
+	}
+	bundleDir := t.TempDir()
+	if err := mirror.Mirror(t.Context(), bundleDir); err != nil {
+		t.Fatalf("Failed to build bundle: %v", err)
+	}
+
+	manifest, err := readBundle(bundleDir, cfg)
+	if err != nil {
+		t.Fatalf("Failed to read bundle: %v", err)
+	}
+	math := manifest.Component(config.TypeExtension, "Math")
+	if math == nil || math.Dir != "extensions/Math" || math.Commit != "6ef1a2b" {
+		t.Fatalf("Unexpected bundled component: %+v", math)
+	}
+
+	// Installing from the bundle needs neither mirror
+	install := func(extensions ...config.ComponentConfig) (*Updater, string, error) {
+		u := &Updater{
+			logger:    logging.Discard,
+			config:    &config.Config{MediaWiki: cfg.MediaWiki, Extensions: extensions},
+			extractor: extractor.NewExtractor(),
+			report:    &Report{},
+			bundleDir: bundleDir,
+			bundle:    manifest,
+		}
+		tempDir := t.TempDir()
+		return u, tempDir, u.installBundle(t.Context(), tempDir)
+	}
+
+	u, tempDir, err := install(cfg.Extensions...)
+	if err != nil {
+		t.Fatalf("Failed to install bundle: %v", err)
+	}
+	for _, file := range []string{"composer.json", "extensions/Math/extension.json"} {
+		if _, err := os.Stat(filepath.Join(tempDir, file)); err != nil {
+			t.Errorf("Expected %s to be unpacked: %v", file, err)
+		}
+	}
+	if len(u.staged) != 1 || u.report.Components[0].Commit != "6ef1a2b" {
+		t.Errorf("Unexpected report: %+v", u.report.Components)
+	}
+
+	missing := config.ComponentConfig{Type: config.TypeExtension, Distributor: "extdist", Name: "Echo", Required: true}
+	if _, _, err := install(missing); err == nil || !strings.Contains(err.Error(), "not in bundle") {
+		t.Errorf("Expected a required component missing from the bundle to fail, got %v", err)
+	}
+
+	if _, err := readBundle(bundleDir, &config.Config{MediaWiki: config.MediaWikiConfig{Version: "1.42.3"}}); err == nil {
+		t.Error("Expected a bundle for another MediaWiki version to be rejected")
+	}
+}
+
+func TestMirrorCancelled(t *testing.T) {
+	releases, extdist := t.TempDir(), t.TempDir()
+	writeArchive(t, "mediawiki-1.43.1", "composer.json", releases, "1.43/mediawiki-1.43.1.tar.gz")
+	writeArchive(t, "Math", "extension.json", extdist, "extensions/Math-REL1_43-6ef1a2b.tar.gz")
+
+	sources := config.SourcesConfig{Releases: []string{releases}, ExtDist: []string{extdist}, ExtDistAPI: []string{config.NoSource}}
+	u := &Updater{
+		logger: logging.Discard,
+		config: &config.Config{
+			MediaWiki:  config.MediaWikiConfig{Version: "1.43.1"},
+			Extensions: []config.ComponentConfig{{Type: config.TypeExtension, Distributor: "extdist", Name: "Math"}},
+		},
+		downloader: newTestDownloader(t, DownloaderOptions(sources, false, nil)),
+		extractor:  extractor.NewExtractor(),
+		mwParser:   mediawiki.NewParser(ParserOptions(sources, nil)),
+		report:     &Report{},
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	bundleDir := t.TempDir()
+	if err := u.Mirror(ctx, bundleDir); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the mirror to stop with context.Canceled, got %v", err)
+	}
+	if len(u.report.Components) != 0 {
+		t.Errorf("Expected no component to be recorded after cancellation, got %+v", u.report.Components)
+	}
+	if _, err := os.Stat(filepath.Join(bundleDir, bundle.ManifestFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected no manifest to be written, got %v", err)
+	}
+}
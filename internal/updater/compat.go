@@ -0,0 +1,82 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SKevo18/mediawiki-updater/internal/manifest"
+)
+
+// checkCompatibility checks the requirements declared in the extension.json and skin.json files
+// of the staged components against the MediaWiki version, the PHP runtime and the other installed
+// components. Problems are recorded in the run report; in strict mode they abort the update
+// before anything is replaced.
+func (u *Updater) checkCompatibility(ctx context.Context, tempDir, targetDir string) error {
+	if len(u.staged) == 0 {
+		return nil
+	}
+
+	u.logger.Printf("Checking extension and skin requirements...\n")
+
+	env := manifest.Environment{
+		MediaWiki: u.config.MediaWiki.Version,
+		Lookup: func(componentType, name string) (string, bool) {
+			return installedVersion(componentType, name, tempDir, targetDir)
+		},
+	}
+
+	if info, err := u.php(ctx); err != nil {
+		u.logger.Printf("  PHP requirements are not checked: %v\n", err)
+	} else {
+		env.PHP, env.PHPExtensions = info.Version, info.Extensions
+	}
+
+	incompatible := 0
+	for _, staged := range u.staged {
+		var problems []string
+		m, err := manifest.Read(filepath.Join(tempDir, staged.dir), staged.component.Type)
+		switch {
+		case err != nil:
+			problems = []string{err.Error()}
+		case m != nil:
+			problems = m.Check(env)
+		}
+
+		if len(problems) == 0 {
+			continue
+		}
+
+		incompatible++
+		entry := &u.report.Components[staged.report]
+		entry.Status = StatusIncompatible
+		entry.Problems = problems
+		for _, problem := range problems {
+			u.logger.Printf("  WARNING: %s %s\n", staged.dir, problem)
+		}
+	}
+
+	if incompatible > 0 && u.strict {
+		return fmt.Errorf("%d component(s) have %w, aborting before anything is replaced (strict mode)", incompatible, ErrIncompatible)
+	}
+	return nil
+}
+
+// installedVersion returns the version of an extension or skin that is staged in tempDir,
+// bundled with MediaWiki core or already installed in targetDir, and whether it was found
+func installedVersion(componentType, name, tempDir, targetDir string) (string, bool) {
+	for _, root := range []string{tempDir, targetDir} {
+		dir := filepath.Join(root, componentType+"s", name)
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+
+		m, err := manifest.Read(dir, componentType)
+		if err != nil || m == nil {
+			return "", true
+		}
+		return m.Version, true
+	}
+	return "", false
+}
@@ -0,0 +1,69 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/downloader"
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	tempDir, targetDir := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		filepath.Join(tempDir, "extensions/Math/extension.json"):   `{"name": "Math", "requires": {"MediaWiki": ">= 1.44", "extensions": {"Cite": "*"}}}`,
+		filepath.Join(tempDir, "extensions/Echo/extension.json"):   `{"name": "Echo", "version": "2.0", "requires": {"MediaWiki": ">= 1.43"}}`,
+		filepath.Join(targetDir, "extensions/Cite/extension.json"): `{"name": "Cite", "version": "1.0.0"}`,
+		filepath.Join(tempDir, "skins/Citizen/skin.json"):          `{"name": "Citizen", "requires": {"skins": {"Vector": "*"}}}`,
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create %s: %v", path, err)
+		}
+	}
+
+	newUpdater := func(strict bool) *Updater {
+		u := &Updater{
+			logger:    logging.Discard,
+			config:    &config.Config{MediaWiki: config.MediaWikiConfig{Version: "1.43.1"}},
+			strict:    strict,
+			phpBinary: filepath.Join(t.TempDir(), "missing-php"),
+			report:    &Report{},
+		}
+		for _, component := range []config.ComponentConfig{
+			{Type: config.TypeExtension, Distributor: "extdist", Name: "Math"},
+			{Type: config.TypeExtension, Distributor: "extdist", Name: "Echo"},
+			{Type: config.TypeSkin, Distributor: "extdist", Name: "Citizen"},
+		} {
+			u.report.addComponent(component, nil, nil)
+			u.stage(tempDir, component, &downloader.Result{Dir: filepath.Join(tempDir, component.Type+"s", component.Name)})
+		}
+		return u
+	}
+
+	u := newUpdater(false)
+	if err := u.checkCompatibility(t.Context(), tempDir, targetDir); err != nil {
+		t.Fatalf("Expected no error outside of strict mode, got %v", err)
+	}
+
+	math := u.report.Components[0]
+	if math.Status != StatusIncompatible || strings.Join(math.Problems, "\n") != "requires MediaWiki >= 1.44 (found 1.43.1)" {
+		t.Errorf("Unexpected report for Math: %+v", math)
+	}
+	if echo := u.report.Components[1]; echo.Status != StatusInstalled || len(echo.Problems) > 0 {
+		t.Errorf("Unexpected report for Echo: %+v", echo)
+	}
+	if citizen := u.report.Components[2]; len(citizen.Problems) != 1 || !strings.Contains(citizen.Problems[0], "skin Vector, which is not installed") {
+		t.Errorf("Unexpected report for Citizen: %+v", citizen)
+	}
+
+	if err := newUpdater(true).checkCompatibility(t.Context(), tempDir, targetDir); err == nil || !strings.Contains(err.Error(), "2 component(s)") {
+		t.Errorf("Expected strict mode to fail for 2 components, got %v", err)
+	}
+}
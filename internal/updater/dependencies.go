@@ -0,0 +1,332 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+	"github.com/SKevo18/mediawiki-updater/internal/manifest"
+	"github.com/SKevo18/mediawiki-updater/internal/version"
+)
+
+// dependency is a requirement of one component on another, keyed like "extension/Math"
+type dependency struct {
+	from       string
+	to         string
+	constraint string
+}
+
+// componentRepoPattern matches Git URLs of Wikimedia-hosted components, like
+// https://github.com/wikimedia/mediawiki-extensions-Math.git or
+// https://gerrit.wikimedia.org/r/mediawiki/extensions/Math
+var componentRepoPattern = regexp.MustCompile(`^(.*[/-])(extensions|skins)([/-])([^/]+?)(\.git)?/?$`)
+
+// resolveDependencies reads the manifests of the staged components and downloads the extensions
+// and skins they require that are neither configured, staged, bundled with MediaWiki nor installed
+// in targetDir, from the same distributor and branch as the component that requires them. Added
+// components are checked in turn. Dependency cycles through added components and added components
+// that do not satisfy every version constraint on them abort the update before anything is
+// replaced. targetDir may be empty to download every missing dependency.
+func (u *Updater) resolveDependencies(ctx context.Context, tempDir, targetDir string) error {
+	u.logger.Printf("Resolving dependencies...\n")
+
+	versionTag, err := u.getVersionTag()
+	if err != nil {
+		return err
+	}
+
+	configured := make(map[string]bool)
+	for _, component := range append(slices.Clone(u.config.Extensions), u.config.Skins...) {
+		configured[componentKey(component.Type, component.DirName())] = true
+	}
+
+	// Components are required by the name in their manifest, which may differ from their directory
+	manifests := make([]*manifest.Manifest, len(u.staged))
+	for i, staged := range u.staged {
+		configured[componentKey(staged.component.Type, filepath.Base(staged.dir))] = true
+		manifests[i], _ = manifest.Read(filepath.Join(tempDir, staged.dir), staged.component.Type)
+		if manifests[i] != nil && manifests[i].Name != "" {
+			configured[componentKey(staged.component.Type, manifests[i].Name)] = true
+		}
+	}
+
+	var edges []dependency
+	added := make(map[string]config.ComponentConfig)
+	installed := make(map[string]bool)
+	addedBy := make(map[string]string)
+	reportIndex := make(map[string]int)
+
+	// u.staged grows while added components are downloaded
+	for i := 0; i < len(u.staged); i++ {
+		staged := u.staged[i]
+
+		var m *manifest.Manifest
+		if i < len(manifests) {
+			m = manifests[i]
+		} else {
+			// Invalid manifests are reported by the compatibility check
+			m, _ = manifest.Read(filepath.Join(tempDir, staged.dir), staged.component.Type)
+		}
+		if m == nil {
+			continue
+		}
+
+		from := componentKey(staged.component.Type, filepath.Base(staged.dir))
+		if m.Name != "" {
+			from = componentKey(staged.component.Type, m.Name)
+		}
+
+		for _, required := range m.Dependencies() {
+			to := componentKey(required.Type, required.Name)
+			edges = append(edges, dependency{from, to, required.Constraint})
+
+			component := dependencyComponent(staged.component, required.Type, required.Name)
+			if queued, ok := added[to]; ok {
+				if describeSource(queued) != describeSource(component) {
+					return fmt.Errorf("conflicting versions of %s: %s requires %s, %s requires %s",
+						to, addedBy[to], describeSource(queued), from, describeSource(component))
+				}
+				continue
+			}
+
+			componentsDir := filepath.Join(tempDir, required.Type+"s")
+			if configured[to] {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(componentsDir, required.Name)); err == nil {
+				// Bundled with MediaWiki core
+				continue
+			}
+			if targetDir != "" {
+				if _, err := os.Stat(filepath.Join(targetDir, required.Type+"s", required.Name)); err == nil {
+					installed[to] = true
+					continue
+				}
+			}
+
+			if err := os.MkdirAll(componentsDir, 0o755); err != nil {
+				return err
+			}
+
+			added[to], addedBy[to] = component, from
+			u.logger.Printf("  %s requires %s, adding it\n", from, to)
+			if err := u.downloadComponent(ctx, tempDir, componentsDir, versionTag, component); err != nil {
+				return err
+			}
+			reportIndex[to] = len(u.report.Components) - 1
+		}
+	}
+
+	for _, edge := range edges {
+		if index, ok := reportIndex[edge.to]; ok {
+			entry := &u.report.Components[index]
+			if !slices.Contains(entry.RequiredBy, edge.from) {
+				entry.RequiredBy = append(entry.RequiredBy, edge.from)
+			}
+		}
+	}
+
+	writeDependencyTree(logging.Writer(u.logger), edges, func(key string) string {
+		if _, ok := added[key]; ok {
+			return " (added)"
+		}
+		if installed[key] {
+			return " (installed)"
+		}
+		if !configured[key] {
+			return " (bundled)"
+		}
+		return ""
+	})
+
+	if cycle := findCycle(edges, func(key string) bool {
+		_, ok := added[key]
+		return ok
+	}); cycle != nil {
+		return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	return checkAddedVersions(tempDir, edges, added)
+}
+
+// componentKey returns the key of a component in the dependency graph, e.g. "extension/Math"
+func componentKey(componentType, name string) string {
+	return componentType + "/" + name
+}
+
+// dependencyComponent returns the component to download for a dependency of parent. Dependencies
+// of Wikimedia-hosted Git components are fetched from the sibling repository at the same ref;
+// all others are downloaded from ExtDist, using the REL branch of the parent if it has one.
+func dependencyComponent(parent config.ComponentConfig, componentType, name string) config.ComponentConfig {
+	component := config.ComponentConfig{
+		Type:        componentType,
+		Distributor: "extdist",
+		Name:        name,
+		Required:    parent.Required,
+	}
+
+	switch parent.Distributor {
+	case "extdist":
+		component.Version = parent.Version
+	case "git":
+		if match := componentRepoPattern.FindStringSubmatch(parent.Name); match != nil {
+			component.Distributor = "git"
+			component.Name = match[1] + componentType + "s" + match[3] + name + match[5]
+			component.Version = parent.Version
+			component.Dir = name
+			component.Auth = parent.Auth
+		} else if strings.HasPrefix(parent.Version, "REL") {
+			component.Version = parent.Version
+		}
+	}
+
+	return component
+}
+
+// describeSource describes where a component is downloaded from, e.g. "extdist REL1_43"
+func describeSource(component config.ComponentConfig) string {
+	source := component.Distributor
+	if component.Distributor == "git" {
+		source += " " + component.Name
+	}
+	if component.Version != "" {
+		source += " " + component.Version
+	}
+	return source
+}
+
+// findCycle returns a dependency cycle through a component for which through returns true,
+// starting and ending with the component of the cycle that comes first in edges, or nil if there
+// is none. Cycles between other components are ignored.
+func findCycle(edges []dependency, through func(key string) bool) []string {
+	graph := make(map[string][]string)
+	var nodes []string
+	for _, edge := range edges {
+		for _, node := range []string{edge.from, edge.to} {
+			if !slices.Contains(nodes, node) {
+				nodes = append(nodes, node)
+			}
+		}
+		graph[edge.from] = append(graph[edge.from], edge.to)
+	}
+
+	for _, start := range nodes {
+		if !through(start) {
+			continue
+		}
+
+		visited := make(map[string]bool)
+		var path []string
+		var visit func(node string) []string
+		visit = func(node string) []string {
+			visited[node] = true
+			path = append(path, node)
+			for _, next := range graph[node] {
+				if next == start {
+					return append(slices.Clone(path), start)
+				}
+				if !visited[next] {
+					if cycle := visit(next); cycle != nil {
+						return cycle
+					}
+				}
+			}
+			path = path[:len(path)-1]
+			return nil
+		}
+
+		if cycle := visit(start); cycle != nil {
+			// Start the cycle at its first component, so that it is reported alike from every start
+			cycle = cycle[:len(cycle)-1]
+			first := 0
+			for i, node := range cycle {
+				if slices.Index(nodes, node) < slices.Index(nodes, cycle[first]) {
+					first = i
+				}
+			}
+			return slices.Concat(cycle[first:], cycle[:first], cycle[first:first+1])
+		}
+	}
+	return nil
+}
+
+// checkAddedVersions checks the version of every added component against the constraints of
+// all components that require it
+func checkAddedVersions(tempDir string, edges []dependency, added map[string]config.ComponentConfig) error {
+	for _, edge := range edges {
+		component, ok := added[edge.to]
+		if !ok || strings.TrimSpace(edge.constraint) == "*" {
+			continue
+		}
+
+		m, err := manifest.Read(filepath.Join(tempDir, component.Type+"s", component.DirName()), component.Type)
+		if err != nil || m == nil || m.Version == "" {
+			// Unknown versions are reported by the compatibility check
+			continue
+		}
+
+		constraint, err := version.ParseConstraint(edge.constraint)
+		if err != nil {
+			continue
+		}
+		found, err := version.Parse(m.Version)
+		if err != nil {
+			continue
+		}
+
+		if !constraint.Check(found) {
+			return fmt.Errorf("conflicting versions of %s: %s requires %s, but %s has version %s",
+				edge.to, edge.from, edge.constraint, describeSource(component), m.Version)
+		}
+	}
+	return nil
+}
+
+// writeDependencyTree writes the components that require others as a tree. marker returns a
+// suffix for a dependency, e.g. " (added)".
+func writeDependencyTree(w io.Writer, edges []dependency, marker func(key string) string) {
+	children := make(map[string][]dependency)
+	isDependency := make(map[string]bool)
+	var roots []string
+	for _, edge := range edges {
+		if _, ok := children[edge.from]; !ok {
+			roots = append(roots, edge.from)
+		}
+		children[edge.from] = append(children[edge.from], edge)
+		isDependency[edge.to] = true
+	}
+
+	if len(roots) == 0 {
+		fmt.Fprintln(w, "  No dependencies.")
+		return
+	}
+
+	fmt.Fprintln(w, "Dependency tree:")
+	var write func(node string, depth int, seen []string)
+	write = func(node string, depth int, seen []string) {
+		for _, edge := range children[node] {
+			constraint := ""
+			if c := strings.TrimSpace(edge.constraint); c != "" && c != "*" {
+				constraint = " " + c
+			}
+			fmt.Fprintf(w, "%s└─ %s%s%s\n", strings.Repeat("   ", depth+1), edge.to, constraint, marker(edge.to))
+			if !slices.Contains(seen, edge.to) {
+				write(edge.to, depth+1, append(seen, edge.to))
+			}
+		}
+	}
+	for _, root := range roots {
+		if isDependency[root] {
+			continue
+		}
+		fmt.Fprintf(w, "  %s\n", root)
+		write(root, 0, []string{root})
+	}
+}
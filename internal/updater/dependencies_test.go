@@ -0,0 +1,215 @@
+package updater
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/downloader"
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+)
+
+// createComponentRepo creates a local Git repository containing an extension.json file
+func createComponentRepo(t *testing.T, dir, manifest string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "extension.json"), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch=REL1_43"},
+		{"add", "."},
+		{"commit", "--quiet", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+}
+
+func TestResolveDependencies(t *testing.T) {
+	tests := []struct {
+		name          string
+		childVersion  string
+		childRequires string
+		err           string
+	}{
+		{"added", "2.1.0", `{"skins": {"Vector": "*"}}`, ""},
+		{"conflict", "1.5.0", `{}`, "conflicting versions of extension/Child: extension/Parent requires >= 2.0"},
+		{"cycle", "2.1.0", `{"extensions": {"Parent": "*"}}`, "dependency cycle: extension/Parent -> extension/Child -> extension/Parent"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repos := t.TempDir()
+			parentRepo := filepath.Join(repos, "mediawiki-extensions-Parent")
+			createComponentRepo(t, parentRepo, `{"name": "Parent", "requires": {"extensions": {"Child": ">= 2.0"}, "skins": {"Vector": "*"}}}`)
+			createComponentRepo(t, filepath.Join(repos, "mediawiki-extensions-Child"), `{"name": "Child", "version": "`+test.childVersion+`", "requires": `+test.childRequires+`}`)
+
+			tempDir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(tempDir, "skins", "Vector"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			parent := config.ComponentConfig{Type: config.TypeExtension, Distributor: "git", Name: parentRepo, Version: "REL1_43"}
+			u := &Updater{
+				logger:     logging.Discard,
+				config:     &config.Config{MediaWiki: config.MediaWikiConfig{Version: "1.43.1"}, Extensions: []config.ComponentConfig{parent}},
+				downloader: newTestDownloader(t, downloader.Options{}),
+				report:     &Report{},
+			}
+			if err := u.downloadComponent(t.Context(), tempDir, filepath.Join(tempDir, "extensions"), "REL1_43", parent); err != nil {
+				t.Fatalf("Failed to download parent: %v", err)
+			}
+
+			err := u.resolveDependencies(t.Context(), tempDir, "")
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("Expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to resolve dependencies: %v", err)
+			}
+
+			if len(u.report.Components) != 2 {
+				t.Fatalf("Expected the child to be added, got %+v", u.report.Components)
+			}
+			child := u.report.Components[1]
+			if child.Status != StatusInstalled || child.Distributor != "git" || strings.Join(child.RequiredBy, ",") != "extension/Parent" {
+				t.Errorf("Unexpected report for the child: %+v", child)
+			}
+			if _, err := os.Stat(filepath.Join(tempDir, "extensions", "Child", "extension.json")); err != nil {
+				t.Errorf("Expected the child to be installed as extensions/Child: %v", err)
+			}
+		})
+	}
+}
+
+func TestResolveDependenciesConfiguredAndInstalled(t *testing.T) {
+	repos := t.TempDir()
+	parentRepo := filepath.Join(repos, "mediawiki-extensions-Parent")
+	childRepo := filepath.Join(repos, "mediawiki-extensions-Child")
+	// The cycle between the configured components is not caused by dependency resolution
+	createComponentRepo(t, parentRepo, `{"name": "Parent", "requires": {"extensions": {"Child": "*", "Installed": "*"}}}`)
+	createComponentRepo(t, childRepo, `{"name": "Child", "requires": {"extensions": {"Parent": "*"}}}`)
+
+	targetDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(targetDir, "extensions", "Installed"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	components := []config.ComponentConfig{
+		{Type: config.TypeExtension, Distributor: "git", Name: parentRepo, Version: "REL1_43"},
+		{Type: config.TypeExtension, Distributor: "git", Name: childRepo, Version: "REL1_43"},
+	}
+	u := &Updater{
+		logger:     logging.Discard,
+		config:     &config.Config{MediaWiki: config.MediaWikiConfig{Version: "1.43.1"}, Extensions: components},
+		downloader: newTestDownloader(t, downloader.Options{}),
+		report:     &Report{},
+	}
+	tempDir := t.TempDir()
+	for _, component := range components {
+		if err := u.downloadComponent(t.Context(), tempDir, filepath.Join(tempDir, "extensions"), "REL1_43", component); err != nil {
+			t.Fatalf("Failed to download %s: %v", component.Name, err)
+		}
+	}
+
+	if err := u.resolveDependencies(t.Context(), tempDir, targetDir); err != nil {
+		t.Fatalf("Failed to resolve dependencies: %v", err)
+	}
+	if len(u.report.Components) != 2 {
+		t.Errorf("Expected no components to be added, got %+v", u.report.Components)
+	}
+}
+
+func TestDependencyComponent(t *testing.T) {
+	tests := []struct {
+		parent   config.ComponentConfig
+		expected string
+	}{
+		{config.ComponentConfig{Distributor: "extdist", Name: "VisualEditor", Version: "REL1_42"}, "extdist REL1_42"},
+		{config.ComponentConfig{Distributor: "extdist", Name: "VisualEditor"}, "extdist"},
+		{config.ComponentConfig{Distributor: "git", Name: "https://github.com/wikimedia/mediawiki-extensions-VisualEditor.git", Version: "master"}, "git https://github.com/wikimedia/mediawiki-skins-Vector.git master"},
+		{config.ComponentConfig{Distributor: "git", Name: "https://gerrit.wikimedia.org/r/mediawiki/extensions/VisualEditor", Version: "REL1_43"}, "git https://gerrit.wikimedia.org/r/mediawiki/skins/Vector REL1_43"},
+		{config.ComponentConfig{Distributor: "git", Name: "https://example.com/VisualEditor.git", Version: "REL1_43"}, "extdist REL1_43"},
+		{config.ComponentConfig{Distributor: "git", Name: "https://example.com/VisualEditor.git", Version: "main"}, "extdist"},
+	}
+
+	for _, test := range tests {
+		component := dependencyComponent(test.parent, config.TypeSkin, "Vector")
+		if actual := describeSource(component); actual != test.expected {
+			t.Errorf("Dependency of %s %s: expected %q, got %q", test.parent.Name, test.parent.Version, test.expected, actual)
+		}
+		if component.DirName() != "Vector" {
+			t.Errorf("Dependency of %s: expected directory Vector, got %s", test.parent.Name, component.DirName())
+		}
+	}
+}
+
+func TestFindCycle(t *testing.T) {
+	all := func(string) bool { return true }
+	edges := []dependency{
+		{from: "extension/A", to: "extension/B"},
+		{from: "extension/B", to: "skin/C"},
+		{from: "extension/B", to: "extension/D"},
+	}
+	if cycle := findCycle(edges, all); cycle != nil {
+		t.Errorf("Expected no cycle, got %v", cycle)
+	}
+
+	edges = append(edges, dependency{from: "extension/D", to: "extension/B"})
+	if cycle := strings.Join(findCycle(edges, all), " -> "); cycle != "extension/B -> extension/D -> extension/B" {
+		t.Errorf("Unexpected cycle: %s", cycle)
+	}
+
+	// The same cycle is reported if it is only found through D
+	only := func(key string) func(string) bool {
+		return func(node string) bool { return node == key }
+	}
+	if cycle := strings.Join(findCycle(edges, only("extension/D")), " -> "); cycle != "extension/B -> extension/D -> extension/B" {
+		t.Errorf("Unexpected cycle through D: %s", cycle)
+	}
+
+	// Cycles that do not pass through the given components are ignored
+	for _, key := range []string{"extension/A", "skin/C"} {
+		if cycle := findCycle(edges, only(key)); cycle != nil {
+			t.Errorf("Expected no cycle through %s, got %v", key, cycle)
+		}
+	}
+}
+
+func TestWriteDependencyTree(t *testing.T) {
+	edges := []dependency{
+		{"extension/A", "extension/B", ">= 1.0"},
+		{"extension/B", "skin/C", "*"},
+	}
+
+	var out strings.Builder
+	writeDependencyTree(&out, edges, func(key string) string {
+		if key == "extension/B" {
+			return " (added)"
+		}
+		return ""
+	})
+
+	expected := "Dependency tree:\n  extension/A\n   └─ extension/B >= 1.0 (added)\n      └─ skin/C\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected tree:\n%s", out.String())
+	}
+}
@@ -0,0 +1,28 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrIncompatible is returned in strict mode if components have unsatisfied requirements
+	ErrIncompatible = errors.New("unsatisfied requirements")
+	// ErrPlatform is returned if the PHP, disk space or permission checks fail
+	ErrPlatform = errors.New("platform requirements are not met")
+)
+
+// ComponentError is returned if a required extension or skin could not be downloaded
+type ComponentError struct {
+	Type string
+	Name string
+	Err  error
+}
+
+func (e *ComponentError) Error() string {
+	return fmt.Sprintf("required %s %s could not be downloaded: %v", e.Type, e.Name, e.Err)
+}
+
+func (e *ComponentError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,50 @@
+package updater
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+)
+
+// runPostInstallHooks runs the post-install commands of all staged components.
+// Failures are reported as warnings and recorded in the run report.
+func (u *Updater) runPostInstallHooks(targetDir string) {
+	for _, staged := range u.staged {
+		if len(staged.component.Post) == 0 {
+			continue
+		}
+
+		componentDir := filepath.Join(targetDir, staged.dir)
+		u.logger.Printf("Running post-install hooks for %s...\n", staged.dir)
+
+		for _, command := range staged.component.Post {
+			u.logger.Printf("  $ %s\n", command)
+			if err := runShellCommand(componentDir, command, logging.Writer(u.logger)); err != nil {
+				u.logger.Printf("    WARNING: Post-install hook failed for %s: %v\n", staged.dir, err)
+				entry := &u.report.Components[staged.report]
+				entry.Status = StatusHookFailed
+				entry.Error = fmt.Sprintf("post-install hook %q failed: %v", command, err)
+				break
+			}
+		}
+	}
+}
+
+// runShellCommand runs a command through the platform shell in dir, writing its output to out
+func runShellCommand(dir, command string, out io.Writer) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	cmd.Dir = dir
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return cmd.Run()
+}
@@ -0,0 +1,184 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/detect"
+)
+
+// writeSettingsFile writes the load statements of all installed components to the configured
+// settings file and adds a single include line for it to LocalSettings.php if it is missing.
+// LocalSettings.php is not modified otherwise.
+func (u *Updater) writeSettingsFile(targetDir string) error {
+	settingsFile := u.config.MediaWiki.SettingsFile
+	if settingsFile == "" {
+		return nil
+	}
+
+	relPath, err := settingsFilePath(settingsFile)
+	if err != nil {
+		return err
+	}
+
+	localSettingsPath := filepath.Join(targetDir, "LocalSettings.php")
+	localSettings, err := os.ReadFile(localSettingsPath)
+	if os.IsNotExist(err) {
+		u.logger.Printf("WARNING: LocalSettings.php not found, skipping %s\n", settingsFile)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read LocalSettings.php: %w", err)
+	}
+
+	u.logger.Printf("Writing load statements to %s...\n", settingsFile)
+
+	content := settingsFileContent(targetDir, u.settingsComponents(), detect.LoadedComponents(string(localSettings)))
+	if err := writeFileAtomic(filepath.Join(targetDir, relPath), []byte(content)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", settingsFile, err)
+	}
+
+	includePath := filepath.ToSlash(relPath)
+	if strings.Contains(string(localSettings), includePath) {
+		return nil
+	}
+
+	u.logger.Printf("Adding include of %s to LocalSettings.php\n", includePath)
+	file, err := os.OpenFile(localSettingsPath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open LocalSettings.php: %w", err)
+	}
+	defer file.Close()
+
+	line := fmt.Sprintf("require_once __DIR__ . %s;\n", phpString("/"+includePath))
+	if len(localSettings) > 0 && !strings.HasSuffix(string(localSettings), "\n") {
+		line = "\n" + line
+	}
+	if _, err := file.WriteString(line); err != nil {
+		return fmt.Errorf("failed to update LocalSettings.php: %w", err)
+	}
+	return file.Close()
+}
+
+// settingsComponents returns the configured components followed by the staged components that
+// are not configured, like the dependencies added by dependency resolution
+func (u *Updater) settingsComponents() []config.ComponentConfig {
+	components := slices.Concat(u.config.Extensions, u.config.Skins)
+	listed := make(map[string]bool, len(components))
+	for _, component := range components {
+		listed[componentKey(component.Type, component.DirName())] = true
+	}
+
+	for _, staged := range u.staged {
+		component := staged.component
+		component.Dir = filepath.Base(staged.dir)
+		if key := componentKey(component.Type, component.Dir); !listed[key] {
+			listed[key] = true
+			components = append(components, component)
+		}
+	}
+	return components
+}
+
+// settingsFilePath checks the configured settings file and returns it as a clean relative path
+func settingsFilePath(settingsFile string) (string, error) {
+	relPath := filepath.Clean(filepath.FromSlash(settingsFile))
+	if filepath.IsAbs(relPath) || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) || !strings.HasSuffix(relPath, ".php") {
+		return "", fmt.Errorf("invalid settings_file %q: expected a .php file inside the installation", settingsFile)
+	}
+	if strings.EqualFold(relPath, "LocalSettings.php") {
+		return "", fmt.Errorf("invalid settings_file %q: LocalSettings.php is never rewritten", settingsFile)
+	}
+	return relPath, nil
+}
+
+// settingsFileContent generates the PHP settings file for the components installed in targetDir.
+// Components that LocalSettings.php already loads are skipped, so they are not loaded twice.
+func settingsFileContent(targetDir string, components []config.ComponentConfig, loaded map[string]bool) string {
+	var content strings.Builder
+	content.WriteString("<?php\n")
+	content.WriteString("// Generated by mediawiki-updater. Do not edit: this file is rewritten on every update.\n")
+	content.WriteString("// Declare settings with the php attribute of a component in the configuration instead.\n")
+
+	for _, component := range components {
+		dirName := component.DirName()
+		typeDir := component.Type + "s"
+		componentDir := filepath.Join(targetDir, typeDir, dirName)
+		if _, err := os.Stat(componentDir); err != nil {
+			continue
+		}
+
+		content.WriteString("\n")
+		if loaded[component.Type+"/"+dirName] {
+			fmt.Fprintf(&content, "// %s is loaded by LocalSettings.php\n", dirName)
+		} else if loadStatement := loadStatement(componentDir, component.Type, typeDir, dirName); loadStatement != "" {
+			content.WriteString(loadStatement + "\n")
+		} else {
+			fmt.Fprintf(&content, "// %s has no %s.json or %s.php entry point\n", dirName, component.Type, dirName)
+		}
+
+		for _, statement := range component.PHP {
+			if !strings.HasSuffix(statement, ";") && !strings.HasSuffix(statement, "}") {
+				statement += ";"
+			}
+			content.WriteString(statement + "\n")
+		}
+	}
+
+	return content.String()
+}
+
+// loadStatement returns the statement that loads the component in componentDir: wfLoadExtension
+// or wfLoadSkin if it has an extension.json or skin.json, or require_once of a legacy entry point
+func loadStatement(componentDir, componentType, typeDir, dirName string) string {
+	if _, err := os.Stat(filepath.Join(componentDir, componentType+".json")); err == nil {
+		function := "wfLoadExtension"
+		if componentType == config.TypeSkin {
+			function = "wfLoadSkin"
+		}
+		return fmt.Sprintf("%s( %s );", function, phpString(dirName))
+	}
+
+	if _, err := os.Stat(filepath.Join(componentDir, dirName+".php")); err == nil {
+		return fmt.Sprintf("require_once \"$IP/\" . %s;", phpString(typeDir+"/"+dirName+"/"+dirName+".php"))
+	}
+
+	return ""
+}
+
+// phpString quotes value as a single-quoted PHP string
+func phpString(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place,
+// so that MediaWiki never reads a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Chmod(0o644); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
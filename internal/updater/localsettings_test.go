@@ -0,0 +1,138 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+)
+
+func TestWriteSettingsFile(t *testing.T) {
+	targetDir := t.TempDir()
+	files := map[string]string{
+		"extensions/Math/extension.json": "{}",
+		"extensions/Legacy/Legacy.php":   "<?php\n",
+		"extensions/Cite/extension.json": "{}",
+		"skins/Citizen/skin.json":        "{}",
+		"LocalSettings.php":              "<?php\n$wgSitename = 'Test';\nwfLoadExtension( 'Cite' );",
+	}
+	for name, content := range files {
+		path := filepath.Join(targetDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	u := &Updater{logger: logging.Discard, config: &config.Config{
+		MediaWiki: config.MediaWikiConfig{SettingsFile: "LocalSettings.extensions.php"},
+		Extensions: []config.ComponentConfig{
+			{Type: config.TypeExtension, Distributor: "extdist", Name: "Math", PHP: []string{"$wgMathValidModes = [ 'source' ]"}},
+			{Type: config.TypeExtension, Distributor: "extdist", Name: "Legacy"},
+			{Type: config.TypeExtension, Distributor: "extdist", Name: "Cite", PHP: []string{"$wgCiteBookReferencing = true;"}},
+			{Type: config.TypeExtension, Distributor: "extdist", Name: "Missing"},
+		},
+		Skins: []config.ComponentConfig{
+			{Type: config.TypeSkin, Distributor: "git", Name: "https://github.com/example/mediawiki-skins-Citizen.git", Dir: "Citizen"},
+		},
+	}}
+
+	// Writing twice must not add a second include line
+	for range 2 {
+		if err := u.writeSettingsFile(targetDir); err != nil {
+			t.Fatalf("Failed to write settings file: %v", err)
+		}
+	}
+
+	generated, err := os.ReadFile(filepath.Join(targetDir, "LocalSettings.extensions.php"))
+	if err != nil {
+		t.Fatalf("Failed to read settings file: %v", err)
+	}
+
+	expected := []string{
+		"wfLoadExtension( 'Math' );\n$wgMathValidModes = [ 'source' ];\n",
+		"require_once \"$IP/\" . 'extensions/Legacy/Legacy.php';\n",
+		"// Cite is loaded by LocalSettings.php\n$wgCiteBookReferencing = true;\n",
+		"wfLoadSkin( 'Citizen' );\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(string(generated), want) {
+			t.Errorf("Expected settings file to contain %q, got:\n%s", want, generated)
+		}
+	}
+	if strings.Contains(string(generated), "Missing") {
+		t.Errorf("Expected components that are not installed to be skipped, got:\n%s", generated)
+	}
+
+	localSettings, err := os.ReadFile(filepath.Join(targetDir, "LocalSettings.php"))
+	if err != nil {
+		t.Fatalf("Failed to read LocalSettings.php: %v", err)
+	}
+	want := "<?php\n$wgSitename = 'Test';\nwfLoadExtension( 'Cite' );\nrequire_once __DIR__ . '/LocalSettings.extensions.php';\n"
+	if string(localSettings) != want {
+		t.Errorf("Expected a single include line to be appended, got:\n%s", localSettings)
+	}
+}
+
+func TestWriteSettingsFileDependencies(t *testing.T) {
+	targetDir := t.TempDir()
+	for name, content := range map[string]string{
+		"extensions/VisualEditor/extension.json": "{}",
+		"extensions/Parsoid/extension.json":      "{}",
+		"LocalSettings.php":                      "<?php\n",
+	} {
+		path := filepath.Join(targetDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	visualEditor := config.ComponentConfig{Type: config.TypeExtension, Distributor: "extdist", Name: "VisualEditor"}
+	u := &Updater{logger: logging.Discard, config: &config.Config{
+		MediaWiki:  config.MediaWikiConfig{SettingsFile: "LocalSettings.extensions.php"},
+		Extensions: []config.ComponentConfig{visualEditor},
+	}}
+	// Parsoid was added by dependency resolution and is not in the configuration
+	u.staged = []stagedComponent{
+		{component: visualEditor, dir: filepath.Join("extensions", "VisualEditor")},
+		{component: dependencyComponent(visualEditor, config.TypeExtension, "Parsoid"), dir: filepath.Join("extensions", "Parsoid")},
+	}
+
+	if err := u.writeSettingsFile(targetDir); err != nil {
+		t.Fatalf("Failed to write settings file: %v", err)
+	}
+
+	generated, err := os.ReadFile(filepath.Join(targetDir, "LocalSettings.extensions.php"))
+	if err != nil {
+		t.Fatalf("Failed to read settings file: %v", err)
+	}
+	for _, want := range []string{"wfLoadExtension( 'VisualEditor' );\n", "wfLoadExtension( 'Parsoid' );\n"} {
+		if strings.Count(string(generated), want) != 1 {
+			t.Errorf("Expected settings file to contain %q once, got:\n%s", want, generated)
+		}
+	}
+}
+
+func TestSettingsFilePath(t *testing.T) {
+	valid := []string{"LocalSettings.extensions.php", "settings/extensions.php"}
+	invalid := []string{"/etc/extensions.php", "../extensions.php", "extensions.txt", "LocalSettings.php"}
+
+	for _, value := range valid {
+		if _, err := settingsFilePath(value); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", value, err)
+		}
+	}
+	for _, value := range invalid {
+		if _, err := settingsFilePath(value); err == nil {
+			t.Errorf("Expected %q to be invalid", value)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SKevo18/mediawiki-updater/internal/doctor"
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+	"github.com/SKevo18/mediawiki-updater/internal/php"
+)
+
+// checkPlatform checks the PHP runtime against the composer.json of the downloaded MediaWiki
+// core, and the free disk space and write permissions of the target directory. Failures abort
+// the update before anything is replaced, unless platform requirements are ignored.
+func (u *Updater) checkPlatform(ctx context.Context, tempDir, targetDir string) error {
+	u.logger.Printf("Checking platform requirements...\n")
+
+	mwVersion := u.config.MediaWiki.Version
+	opts := doctor.Options{
+		TargetDir:     targetDir,
+		PHPBinary:     u.phpBinary,
+		PHPRequired:   u.phpRequired,
+		MediaWiki:     mwVersion,
+		RequiredSpace: doctor.DefaultRequiredSpace,
+	}
+
+	if info, err := u.php(ctx); err == nil {
+		opts.PHP = info
+	}
+
+	if size, err := doctor.DirSize(tempDir); err == nil {
+		opts.RequiredSpace = size
+	}
+
+	// The core archive ships composer.json; fall back to the built-in table if it does not
+	composerJSON, _ := os.ReadFile(filepath.Join(tempDir, "composer.json"))
+	if core, err := doctor.CoreRequirements(mwVersion, composerJSON); err != nil {
+		u.logger.Printf("  %v\n", err)
+	} else {
+		opts.Requirements = append(opts.Requirements, core)
+	}
+
+	results := doctor.Run(ctx, opts)
+	doctor.Print(logging.Writer(u.logger), results)
+
+	if doctor.Failed(results) {
+		if u.ignorePlatformReqs {
+			u.logger.Printf("WARNING: Platform requirements are not met, updating anyway\n")
+			return nil
+		}
+		return fmt.Errorf("%w, aborting before anything is replaced (use --ignore-platform-reqs to update anyway)", ErrPlatform)
+	}
+	return nil
+}
+
+// php returns the PHP runtime used for requirement checks, detecting it on first use
+func (u *Updater) php(ctx context.Context) (*php.Info, error) {
+	if u.phpInfo == nil && u.phpErr == nil {
+		u.phpInfo, u.phpErr = php.Detect(ctx, u.phpBinary)
+	}
+	return u.phpInfo, u.phpErr
+}
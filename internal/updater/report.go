@@ -0,0 +1,112 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/downloader"
+)
+
+// Component statuses recorded in the run report
+const (
+	StatusInstalled    = "installed"
+	StatusFailed       = "failed"
+	StatusHookFailed   = "hook failed"
+	StatusIncompatible = "incompatible" // installed, but requirements from extension.json or skin.json are not met
+)
+
+// Report records the outcome of an update run
+type Report struct {
+	MediaWiki  string            `json:"mediawiki"`
+	Components []ComponentReport `json:"components"`
+}
+
+// ComponentReport records the outcome for a single extension or skin
+type ComponentReport struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Distributor string `json:"distributor"`
+	Version     string `json:"version,omitempty"`
+	// RequestedVersion is the ExtDist branch that was requested if a fallback branch was installed instead
+	RequestedVersion string `json:"requested_version,omitempty"`
+	Commit           string `json:"commit,omitempty"`
+	URL              string `json:"url,omitempty"`
+	Status           string `json:"status"`
+	Error            string `json:"error,omitempty"`
+	// Problems are the unsatisfied requirements from extension.json or skin.json
+	Problems []string `json:"problems,omitempty"`
+	// RequiredBy lists the components that caused a dependency to be added, e.g. "extension/VisualEditor"
+	RequiredBy []string `json:"required_by,omitempty"`
+}
+
+// addComponent records the result of downloading a component
+func (r *Report) addComponent(component config.ComponentConfig, result *downloader.Result, err error) {
+	entry := ComponentReport{
+		Type:        component.Type,
+		Name:        component.Name,
+		Distributor: component.Distributor,
+		Version:     component.Version,
+		Status:      StatusInstalled,
+	}
+
+	if result != nil {
+		entry.Version = result.Version
+		entry.Commit = result.Commit
+		entry.URL = result.URL
+		entry.RequestedVersion = result.Requested
+	}
+
+	if err != nil {
+		entry.Status = StatusFailed
+		entry.Error = err.Error()
+	}
+
+	r.Components = append(r.Components, entry)
+}
+
+// Print writes a human-readable summary of the report
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "\nRun report (MediaWiki %s):\n", r.MediaWiki)
+	if len(r.Components) == 0 {
+		fmt.Fprintln(w, "  No extensions or skins configured.")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  TYPE\tNAME\tVERSION\tCOMMIT\tSTATUS")
+	for _, c := range r.Components {
+		commit := c.Commit
+		if commit == "" {
+			commit = "-"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", c.Type, c.Name, c.Version, commit, c.Status)
+	}
+	tw.Flush()
+
+	for _, c := range r.Components {
+		if c.RequestedVersion != "" {
+			fmt.Fprintf(w, "  %s %s: WARNING: installed fallback %s, ExtDist has no %s snapshot\n", c.Type, c.Name, c.Version, c.RequestedVersion)
+		}
+		for _, problem := range c.Problems {
+			fmt.Fprintf(w, "  %s %s %s\n", c.Type, c.Name, problem)
+		}
+	}
+}
+
+// WriteFile writes the report as JSON to the given path
+func (r *Report) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return nil
+}
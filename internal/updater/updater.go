@@ -1,15 +1,23 @@
 package updater
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
 
+	"github.com/SKevo18/mediawiki-updater/internal/bundle"
 	"github.com/SKevo18/mediawiki-updater/internal/config"
 	"github.com/SKevo18/mediawiki-updater/internal/downloader"
+	"github.com/SKevo18/mediawiki-updater/internal/extdist"
 	"github.com/SKevo18/mediawiki-updater/internal/extractor"
+	"github.com/SKevo18/mediawiki-updater/internal/httputil"
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
 	"github.com/SKevo18/mediawiki-updater/internal/mediawiki"
+	"github.com/SKevo18/mediawiki-updater/internal/php"
+	"github.com/SKevo18/mediawiki-updater/internal/progress"
 )
 
 // Updater manages the MediaWiki update process
@@ -19,6 +27,27 @@ type Updater struct {
 	extractor   *extractor.Extractor
 	mwParser    *mediawiki.Parser
 	ignorePaths []string
+	strict      bool
+	withDeps    bool
+	report      *Report
+	staged      []stagedComponent
+	logger      logging.Logger
+
+	phpBinary          string
+	phpRequired        bool      // the PHP binary was configured, not taken from the PATH
+	phpInfo            *php.Info // detected on first use
+	phpErr             error
+	ignorePlatformReqs bool
+
+	bundleDir string
+	bundle    *bundle.Manifest // set when installing from a bundle
+}
+
+// stagedComponent is a downloaded component waiting to be copied to the target directory
+type stagedComponent struct {
+	component config.ComponentConfig
+	dir       string // directory relative to the installation root
+	report    int    // index of the component in the run report
 }
 
 // Options contains configuration options for the updater
@@ -26,13 +55,96 @@ type Options struct {
 	ConfigPath  string
 	TargetDir   string
 	IgnorePaths []string
+	// UseGitBinary fetches Git components with the git binary instead of the built-in implementation
+	UseGitBinary bool
+	// Profile is the name of a configuration profile to merge onto the base configuration
+	Profile string
+	// Strict aborts the update before anything is replaced if a component's requirements are not met
+	Strict bool
+	// WithDependencies downloads the extensions and skins required by the configured components
+	WithDependencies bool
+	// PHPBinary is the PHP binary used to check requirements, overriding the configuration
+	PHPBinary string
+	// IgnorePlatformReqs updates even if PHP, disk space or permission checks fail
+	IgnorePlatformReqs bool
+	// Bundle is a directory written by Mirror to install from instead of downloading
+	Bundle string
+	// Sources overrides the upstream mirrors of the [sources] section
+	Sources config.SourcesConfig
+	// HTTP overrides the HTTP client settings of the [http] section
+	HTTP config.HTTPConfig
+	// Progress is notified of the progress of file downloads; nil reports nothing
+	Progress progress.Reporter
+	// Config is used instead of loading ConfigPath if set. It is not modified.
+	Config *config.Config
+	// HTTPClient sends all requests instead of a client built from the HTTP settings if set
+	HTTPClient httputil.Doer
+	// Logger receives the messages printed while updating; nil prints them to standard output
+	Logger logging.Logger
+	// Distributors download the components whose distributor attribute names them
+	Distributors map[string]downloader.Distributor
 }
 
 // NewUpdater creates a new Updater instance
 func NewUpdater(opts Options) (*Updater, error) {
-	cfg, err := config.LoadConfig(opts.ConfigPath)
+	cfg, err := loadConfig(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load config: %w", err)
+		return nil, err
+	}
+
+	if opts.Profile != "" {
+		cfg, err = cfg.WithProfile(opts.Profile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Components built in code skip the checks of LoadConfig
+	cfg.Extensions = componentDefaults(cfg.Extensions, config.TypeExtension)
+	cfg.Skins = componentDefaults(cfg.Skins, config.TypeSkin)
+	for _, component := range slices.Concat(cfg.Extensions, cfg.Skins) {
+		if err := component.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s %s: %w", component.Type, component.Name, err)
+		}
+	}
+
+	cfg.Sources = cfg.Sources.Override(opts.Sources)
+	if err := cfg.Sources.Check(); err != nil {
+		return nil, err
+	}
+
+	logger := logging.OrStdout(opts.Logger)
+	cfg.HTTP = cfg.HTTP.Override(opts.HTTP)
+	var client *httputil.Client
+	if opts.HTTPClient != nil {
+		client, err = httputil.NewClient(httputil.ClientOptions{Doer: opts.HTTPClient, Logger: logger})
+	} else {
+		client, err = NewHTTPClient(cfg.HTTP, cfg.Sources, logger)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MediaWiki.SettingsFile != "" {
+		if _, err := settingsFilePath(cfg.MediaWiki.SettingsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var manifest *bundle.Manifest
+	if opts.Bundle != "" {
+		if manifest, err = readBundle(opts.Bundle, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	phpBinary := opts.PHPBinary
+	if phpBinary == "" {
+		phpBinary = cfg.MediaWiki.PHP
+	}
+	phpRequired := phpBinary != ""
+	if phpBinary == "" {
+		phpBinary = php.DefaultBinary
 	}
 
 	ignorePaths := opts.IgnorePaths
@@ -40,67 +152,255 @@ func NewUpdater(opts Options) (*Updater, error) {
 		ignorePaths = []string{"LocalSettings.php", ".htaccess", "images"}
 	}
 
+	downloaderOpts := DownloaderOptions(cfg.Sources, opts.UseGitBinary, client)
+	downloaderOpts.Progress = opts.Progress
+	downloaderOpts.Logger = logger
+	downloaderOpts.Distributors = opts.Distributors
+	d, err := downloader.NewDownloader(downloaderOpts)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Updater{
 		config:      cfg,
-		downloader:  downloader.NewDownloader(),
+		downloader:  d,
 		extractor:   extractor.NewExtractor(),
-		mwParser:    mediawiki.NewParser(),
+		mwParser:    mediawiki.NewParser(ParserOptions(cfg.Sources, client)),
 		ignorePaths: ignorePaths,
+		strict:      opts.Strict,
+		withDeps:    opts.WithDependencies,
+		report:      &Report{MediaWiki: cfg.MediaWiki.Version},
+		logger:      logger,
+
+		phpBinary:          phpBinary,
+		phpRequired:        phpRequired,
+		ignorePlatformReqs: opts.IgnorePlatformReqs,
+
+		bundleDir: opts.Bundle,
+		bundle:    manifest,
 	}, nil
 }
 
-// Update performs the complete MediaWiki update process
-func (u *Updater) Update(targetDir string) error {
+// loadConfig returns a copy of the configuration of the options, or loads it from ConfigPath
+func loadConfig(opts Options) (*config.Config, error) {
+	if opts.Config != nil {
+		cfg := *opts.Config
+		return &cfg, nil
+	}
+
+	cfg, err := config.LoadConfig(opts.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return cfg, nil
+}
+
+// componentDefaults returns a copy of components with the type and the extdist distributor set
+// where they are missing, as when they are loaded from a configuration file
+func componentDefaults(components []config.ComponentConfig, componentType string) []config.ComponentConfig {
+	components = slices.Clone(components)
+	for i := range components {
+		if components[i].Type == "" {
+			components[i].Type = componentType
+		}
+		if components[i].Distributor == "" {
+			components[i].Distributor = "extdist"
+		}
+	}
+	return components
+}
+
+// NewHTTPClient creates an HTTP client with the given settings and the rate limits of the
+// sources, logging its warnings to logger
+func NewHTTPClient(settings config.HTTPConfig, sources config.SourcesConfig, logger logging.Logger) (*httputil.Client, error) {
+	if err := settings.Check(); err != nil {
+		return nil, err
+	}
+	return httputil.NewClient(httputil.ClientOptions{
+		Proxy:           settings.Proxy,
+		NoProxy:         settings.NoProxy,
+		CAFiles:         settings.CAFiles,
+		ClientCert:      settings.ClientCert,
+		ClientKey:       settings.ClientKey,
+		UserAgentSuffix: settings.UserAgentSuffix,
+		Limits:          httputil.Limits{Rate: settings.RateLimit, Burst: settings.Burst},
+		HostLimits:      hostLimits(settings, sources),
+		MaxConcurrent:   settings.MaxConcurrent,
+		MaxRetries:      settings.MaxRetries,
+		Logger:          logger,
+	})
+}
+
+// hostLimits returns the limits of the hosts of every source with its own rate limit: the hosts
+// of its mirrors, or of the official endpoint if it has none
+func hostLimits(settings config.HTTPConfig, sources config.SourcesConfig) map[string]httputil.Limits {
+	endpoints := map[string][]string{
+		"releases":    {mediawiki.BaseDownloadURL},
+		"extdist":     {extdist.IndexURL},
+		"extdist_api": {extdist.APIURL},
+		"composer":    {mediawiki.ComposerBaseURL},
+	}
+	for key, mirrors := range map[string][]string{
+		"releases":    sources.Releases,
+		"extdist":     sources.ExtDist,
+		"extdist_api": sources.ExtDistAPI,
+		"composer":    sources.Composer,
+	} {
+		if len(mirrors) > 0 {
+			endpoints[key] = mirrors
+		}
+	}
+
+	limits := make(map[string]httputil.Limits)
+	for key, rate := range sources.RateLimits {
+		for _, endpoint := range endpoints[key] {
+			if parsed, err := url.Parse(endpoint); err == nil && parsed.Host != "" {
+				limits[parsed.Host] = httputil.Limits{Rate: rate, Burst: settings.Burst}
+			}
+		}
+	}
+	return limits
+}
+
+// DownloaderOptions returns the downloader options that use the given mirrors and HTTP client
+func DownloaderOptions(sources config.SourcesConfig, useGitBinary bool, client *httputil.Client) downloader.Options {
+	return downloader.Options{
+		UseGitBinary:   useGitBinary,
+		ExtDistAPIs:    sources.ExtDistAPI,
+		ExtDistMirrors: sources.ExtDist,
+		HTTPClient:     client,
+	}
+}
+
+// ParserOptions returns the release page parser options that use the given mirrors and HTTP client
+func ParserOptions(sources config.SourcesConfig, client *httputil.Client) mediawiki.Options {
+	return mediawiki.Options{Releases: sources.Releases, Composer: sources.Composer, HTTPClient: client}
+}
+
+// Report returns the report of the last update run
+func (u *Updater) Report() *Report {
+	return u.report
+}
+
+// Update performs the complete MediaWiki update process. If ctx is cancelled before the contents
+// are copied to targetDir, the update stops and targetDir is left untouched; once copying has
+// started, the update runs to completion.
+func (u *Updater) Update(ctx context.Context, targetDir string) error {
+	u.reset()
+
 	tempDir, err := os.MkdirTemp("", "mediawiki-temp-")
 	if err != nil {
 		return fmt.Errorf("failed to create temporary directory: %w", err)
 	}
 	defer os.RemoveAll(tempDir)
 
-	// Download MediaWiki core
-	if err := u.downloadMediaWikiCore(tempDir); err != nil {
+	// Download MediaWiki core and the components, or unpack them from the bundle
+	if u.bundle != nil {
+		err = u.installBundle(ctx, tempDir)
+	} else {
+		err = u.download(ctx, tempDir, targetDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Check PHP, disk space and permissions
+	if err := u.checkPlatform(ctx, tempDir, targetDir); err != nil {
+		return err
+	}
+
+	// Check the requirements of the downloaded components
+	if err := u.checkCompatibility(ctx, tempDir, targetDir); err != nil {
+		return err
+	}
+
+	// Last chance to stop before anything in the target directory is replaced
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Copy contents to target directory
+	if err := u.extractor.CopyContents(tempDir, targetDir, u.ignorePaths); err != nil {
+		return fmt.Errorf("failed to copy contents: %w", err)
+	}
+
+	// Run post-install hooks in the installed component directories
+	u.runPostInstallHooks(targetDir)
+
+	// Write load statements for the installed components, if enabled
+	if err := u.writeSettingsFile(targetDir); err != nil {
+		return fmt.Errorf("failed to write load statements: %w", err)
+	}
+
+	return nil
+}
+
+// reset clears the report and the staged components of a previous run
+func (u *Updater) reset() {
+	u.report = &Report{MediaWiki: u.config.MediaWiki.Version}
+	u.staged = nil
+	// A PHP runtime that could not be detected, e.g. because the run was cancelled, is retried
+	u.phpErr = nil
+}
+
+// download downloads MediaWiki core and the configured extensions and skins into tempDir, to be
+// installed into targetDir
+func (u *Updater) download(ctx context.Context, tempDir, targetDir string) error {
+	if err := u.downloadMediaWikiCore(ctx, tempDir); err != nil {
 		return fmt.Errorf("failed to download MediaWiki core: %w", err)
 	}
+	return u.downloadComponents(ctx, tempDir, targetDir)
+}
+
+// downloadComponents downloads the configured extensions and skins, and their dependencies if
+// enabled. Dependencies already installed in targetDir are not downloaded; targetDir may be empty.
+func (u *Updater) downloadComponents(ctx context.Context, tempDir, targetDir string) error {
+	// Look up all ExtDist archives at once
+	if err := u.downloader.PrefetchExtDist(ctx, slices.Concat(u.config.Extensions, u.config.Skins)); err != nil {
+		u.logger.Printf("WARNING: Failed to look up ExtDist archives: %v\n", err)
+	}
 
 	// Download extensions
-	if err := u.downloadExtensions(tempDir); err != nil {
+	if err := u.downloadExtensions(ctx, tempDir); err != nil {
 		return fmt.Errorf("failed to download extensions: %w", err)
 	}
 
 	// Download skins
-	if err := u.downloadSkins(tempDir); err != nil {
+	if err := u.downloadSkins(ctx, tempDir); err != nil {
 		return fmt.Errorf("failed to download skins: %w", err)
 	}
 
-	// Copy contents to target directory
-	if err := u.extractor.CopyContents(tempDir, targetDir, u.ignorePaths); err != nil {
-		return fmt.Errorf("failed to copy contents: %w", err)
+	// Download the extensions and skins the downloaded components require, if enabled
+	if u.withDeps {
+		if err := u.resolveDependencies(ctx, tempDir, targetDir); err != nil {
+			return fmt.Errorf("failed to resolve dependencies: %w", err)
+		}
 	}
 
 	return nil
 }
 
 // downloadMediaWikiCore downloads the MediaWiki core
-func (u *Updater) downloadMediaWikiCore(tempDir string) error {
+func (u *Updater) downloadMediaWikiCore(ctx context.Context, tempDir string) error {
 	version := u.config.MediaWiki.Version
 	if version == "" {
 		return fmt.Errorf("MediaWiki version not specified in config")
 	}
 
-	fmt.Printf("Downloading MediaWiki core version %s...\n", version)
+	u.logger.Printf("Downloading MediaWiki core version %s...\n", version)
 
-	downloadURL, err := u.mwParser.GetDownloadURL(version)
+	downloadURL, err := u.mwParser.GetDownloadURL(ctx, version)
 	if err != nil {
 		return err
 	}
 
-	return u.downloader.DownloadAndExtract(downloadURL, tempDir, true)
+	return u.downloader.DownloadAndExtract(ctx, downloadURL, tempDir, true)
 }
 
 // downloadExtensions downloads all configured extensions
-func (u *Updater) downloadExtensions(tempDir string) error {
+func (u *Updater) downloadExtensions(ctx context.Context, tempDir string) error {
 	if len(u.config.Extensions) == 0 {
-		fmt.Println("No extensions configured, skipping...")
+		u.logger.Printf("No extensions configured, skipping...\n")
 		return nil
 	}
 
@@ -114,13 +414,11 @@ func (u *Updater) downloadExtensions(tempDir string) error {
 		return err
 	}
 
-	fmt.Printf("Downloading %d extensions...\n", len(u.config.Extensions))
+	u.logger.Printf("Downloading %d extensions...\n", len(u.config.Extensions))
 
 	for _, ext := range u.config.Extensions {
-		fmt.Printf("  - %s (from %s)\n", ext.Name, ext.Distributor)
-		if err := u.downloader.DownloadComponent(ext, extensionsDir, versionTag); err != nil {
-			fmt.Printf("    WARNING: Failed to download extension %s: %v\n", ext.Name, err)
-			// Continue with other extensions instead of failing completely
+		if err := u.downloadComponent(ctx, tempDir, extensionsDir, versionTag, ext); err != nil {
+			return err
 		}
 	}
 
@@ -128,9 +426,9 @@ func (u *Updater) downloadExtensions(tempDir string) error {
 }
 
 // downloadSkins downloads all configured skins
-func (u *Updater) downloadSkins(tempDir string) error {
+func (u *Updater) downloadSkins(ctx context.Context, tempDir string) error {
 	if len(u.config.Skins) == 0 {
-		fmt.Println("No skins configured, skipping...")
+		u.logger.Printf("No skins configured, skipping...\n")
 		return nil
 	}
 
@@ -144,32 +442,62 @@ func (u *Updater) downloadSkins(tempDir string) error {
 		return err
 	}
 
-	fmt.Printf("Downloading %d skins...\n", len(u.config.Skins))
+	u.logger.Printf("Downloading %d skins...\n", len(u.config.Skins))
 
 	for _, skin := range u.config.Skins {
-		fmt.Printf("  - %s (from %s)\n", skin.Name, skin.Distributor)
-		if err := u.downloader.DownloadComponent(skin, skinsDir, versionTag); err != nil {
-			fmt.Printf("    WARNING: Failed to download skin %s: %v\n", skin.Name, err)
-			// Continue with other skins instead of failing completely
+		if err := u.downloadComponent(ctx, tempDir, skinsDir, versionTag, skin); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-// getVersionTag converts the MediaWiki version to the format used by ExtDist (e.g., "1.43.1" -> "REL1_43")
-func (u *Updater) getVersionTag() (string, error) {
-	version := u.config.MediaWiki.Version
-	if version == "" {
-		return "", fmt.Errorf("MediaWiki version not specified in config")
+// downloadComponent downloads an extension or skin into componentsDir and stages it
+func (u *Updater) downloadComponent(ctx context.Context, tempDir, componentsDir, versionTag string, component config.ComponentConfig) error {
+	component.Fallback = u.config.FallbackBranches(component)
+
+	u.logger.Printf("  - %s (from %s)\n", component.Name, component.Distributor)
+	result, err := u.downloader.DownloadComponent(ctx, component, componentsDir, versionTag)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		// Stop instead of recording every remaining component as failed
+		return ctxErr
+	}
+	return u.record(tempDir, component, result, err)
+}
+
+// record adds a downloaded component to the run report and stages it. A failed download is
+// only returned as an error if the component is required.
+func (u *Updater) record(tempDir string, component config.ComponentConfig, result *downloader.Result, err error) error {
+	if err != nil {
+		u.logger.Printf("    WARNING: Failed to download %s %s: %v\n", component.Type, component.Name, err)
+		// Continue with other components instead of failing completely
+	}
+	u.report.addComponent(component, result, err)
+	if err != nil && component.Required {
+		return &ComponentError{Type: component.Type, Name: component.Name, Err: err}
+	}
+	if err == nil {
+		u.stage(tempDir, component, result)
 	}
+	return nil
+}
 
-	// Extract major.minor version and convert to REL format
-	re := regexp.MustCompile(`^(\d+)\.(\d+)(\.\d+.*)?$`)
-	matches := re.FindStringSubmatch(version)
-	if len(matches) < 3 {
-		return "", fmt.Errorf("invalid version format: %s", version)
+// stage remembers a downloaded component for the post-install phase
+func (u *Updater) stage(tempDir string, component config.ComponentConfig, result *downloader.Result) {
+	dir, err := filepath.Rel(tempDir, result.Dir)
+	if err != nil {
+		dir = result.Dir
 	}
 
-	return fmt.Sprintf("REL%s_%s", matches[1], matches[2]), nil
+	u.staged = append(u.staged, stagedComponent{
+		component: component,
+		dir:       dir,
+		report:    len(u.report.Components) - 1,
+	})
+}
+
+// getVersionTag converts the MediaWiki version to the format used by ExtDist (e.g., "1.43.1" -> "REL1_43")
+func (u *Updater) getVersionTag() (string, error) {
+	return mediawiki.VersionTag(u.config.MediaWiki.Version)
 }
@@ -0,0 +1,297 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/downloader"
+	"github.com/SKevo18/mediawiki-updater/internal/logging"
+	"github.com/SKevo18/mediawiki-updater/internal/mediawiki"
+)
+
+var (
+	// extDistVersionPattern matches ExtDist branch names
+	extDistVersionPattern = regexp.MustCompile(`^(REL\d+_\d+|master)$`)
+	// scpLikeGitURLPattern matches scp-like Git URLs such as git@github.com:user/repo.git
+	scpLikeGitURLPattern = regexp.MustCompile(`^[\w.-]+@[\w.-]+:.+$`)
+)
+
+// knownSections are the sections of an INI configuration file besides per-component and profile sections
+var knownSections = map[string][]string{
+	"mediawiki":  {"version", "settings_file", "php", "fallback"},
+	"extensions": nil,
+	"skins":      nil,
+	"sources":    {"releases", "extdist", "extdist_api", "composer", "releases_rate_limit", "extdist_rate_limit", "extdist_api_rate_limit", "composer_rate_limit"},
+	"http":       {"proxy", "no_proxy", "ca_file", "client_cert", "client_key", "user_agent_suffix", "rate_limit", "burst", "max_concurrent", "max_retries"},
+}
+
+// Problem is an issue found in a configuration file
+type Problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+// String formats the problem as "file:line: message"
+func (p Problem) String() string {
+	switch {
+	case p.File == "":
+		return p.Message
+	case p.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+	default:
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	}
+}
+
+// Offline validates a configuration file without network access. If profile is set, that
+// profile is merged onto the base configuration before the components are checked; every other
+// profile is checked too, so a broken overlay is found before it is deployed.
+// It returns the loaded configuration, or nil if it could not be loaded, and all problems found.
+func Offline(configPath, profile string) (*config.Config, []Problem) {
+	base, err := config.LoadConfig(configPath)
+	if err != nil {
+		// Load errors already include the file name and line number
+		return nil, []Problem{{Message: err.Error()}}
+	}
+
+	cfg := base
+	if profile != "" {
+		cfg, err = base.WithProfile(profile)
+		if err != nil {
+			return nil, []Problem{{File: configPath, Message: err.Error()}}
+		}
+	}
+
+	var problems []Problem
+	if config.Format(configPath) == "ini" {
+		problems = append(problems, checkSections(configPath)...)
+	}
+	problems = append(problems, checkConfig(configPath, cfg)...)
+
+	// Report problems that only show up with another profile, without repeating the base ones
+	reported := make(map[Problem]bool)
+	for _, problem := range problems {
+		reported[problem] = true
+	}
+	for _, name := range base.ProfileNames() {
+		if name == profile {
+			continue
+		}
+
+		merged, err := base.WithProfile(name)
+		if err != nil {
+			problems = append(problems, Problem{File: configPath, Message: err.Error()})
+			continue
+		}
+
+		for _, problem := range checkConfig(configPath, merged) {
+			if !reported[problem] {
+				reported[problem] = true
+				problem.Message = fmt.Sprintf("%s (profile %s)", problem.Message, name)
+				problems = append(problems, problem)
+			}
+		}
+	}
+
+	return cfg, problems
+}
+
+// checkConfig reports problems with the MediaWiki version and the components of a loaded configuration
+func checkConfig(configPath string, cfg *config.Config) []Problem {
+	var problems []Problem
+
+	if _, err := mediawiki.VersionTag(cfg.MediaWiki.Version); err != nil {
+		problems = append(problems, Problem{File: configPath, Message: fmt.Sprintf("invalid [mediawiki] version: %v", err)})
+	}
+
+	seenNames := make(map[string]int)
+	seenDirs := make(map[string]int)
+	for _, component := range slices.Concat(cfg.Extensions, cfg.Skins) {
+		report := func(format string, args ...any) {
+			problems = append(problems, Problem{File: component.File, Line: component.Line, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if !downloader.IsBuiltin(component.Distributor) {
+			report("unknown distributor %q for %s %s (expected extdist or git)", component.Distributor, component.Type, component.Name)
+			continue
+		}
+
+		if err := checkVersion(component); err != nil {
+			report("%v", err)
+		}
+
+		if component.Distributor == "git" && !isGitURL(component.Name) {
+			report("invalid git repository URL %q", component.Name)
+		}
+
+		nameKey := component.Type + "\x00" + component.Distributor + "\x00" + strings.ToLower(component.Name)
+		if line, exists := seenNames[nameKey]; exists {
+			if line > 0 {
+				report("duplicate %s %s (first declared on line %d)", component.Type, component.Name, line)
+			} else {
+				report("duplicate %s %s", component.Type, component.Name)
+			}
+			continue
+		}
+		seenNames[nameKey] = component.Line
+
+		dirName := component.DirName()
+		dirKey := component.Type + "\x00" + strings.ToLower(dirName)
+		if line, exists := seenDirs[dirKey]; exists {
+			report("%s %s installs into %s, which is already used by another component%s", component.Type, component.Name, dirName, onLine(line))
+			continue
+		}
+		seenDirs[dirKey] = component.Line
+	}
+
+	return problems
+}
+
+// onLine describes the line a component was declared on, or nothing if it is not known, like for
+// TOML and JSON files
+func onLine(line int) string {
+	if line == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" on line %d", line)
+}
+
+// Online checks that every component can be resolved from its distributor: ExtDist archives
+// must exist for the configured or derived REL branch and Git references must be reachable.
+// Progress is written to logger.
+func Online(ctx context.Context, cfg *config.Config, d *downloader.Downloader, logger logging.Logger) []Problem {
+	versionTag, err := mediawiki.VersionTag(cfg.MediaWiki.Version)
+	if err != nil {
+		// Already reported by Offline
+		return nil
+	}
+
+	var problems []Problem
+	for _, component := range slices.Concat(cfg.Extensions, cfg.Skins) {
+		if !d.HasDistributor(component.Distributor) {
+			continue
+		}
+
+		logger.Printf("Checking %s %s (from %s)...\n", component.Type, component.Name, component.Distributor)
+		component.Fallback = cfg.FallbackBranches(component)
+		if _, err := d.Resolve(ctx, component, versionTag); err != nil {
+			problems = append(problems, Problem{
+				File:    component.File,
+				Line:    component.Line,
+				Message: fmt.Sprintf("%s %s cannot be resolved: %v", component.Type, component.Name, err),
+			})
+		}
+	}
+
+	return problems
+}
+
+// checkSections reports unknown sections and keys in an INI configuration file
+func checkSections(configPath string) []Problem {
+	ini, err := config.LoadINIFile(configPath)
+	if err != nil {
+		return []Problem{{File: configPath, Message: err.Error()}}
+	}
+
+	var problems []Problem
+	for _, sectionName := range ini.SectionNames() {
+		if config.IsComponentSection(sectionName) {
+			continue
+		}
+
+		keys, known := knownSections[sectionName]
+		if config.IsProfileSection(sectionName) {
+			// [profile:<name>] overrides the version, [profile:<name> extensions] lists components
+			keys, known = nil, true
+			if !strings.ContainsAny(sectionName, " \t") {
+				keys = knownSections["mediawiki"]
+			}
+		}
+		if !known {
+			problems = append(problems, Problem{
+				File:    ini.SectionFile(sectionName),
+				Line:    ini.SectionLine(sectionName),
+				Message: fmt.Sprintf("unknown section [%s]", sectionName),
+			})
+			continue
+		}
+
+		if keys == nil {
+			continue
+		}
+		for _, entry := range ini.GetEntries(sectionName) {
+			if !slices.Contains(keys, entry.Key) {
+				problems = append(problems, Problem{
+					File:    entry.File,
+					Line:    entry.Line,
+					Message: fmt.Sprintf("unknown key %q in [%s]", entry.Key, sectionName),
+				})
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkVersion reports malformed component versions
+func checkVersion(component config.ComponentConfig) error {
+	if component.Version == "" {
+		return nil
+	}
+
+	switch component.Distributor {
+	case "extdist":
+		if !extDistVersionPattern.MatchString(component.Version) {
+			return fmt.Errorf("invalid ExtDist version %q for %s (expected REL<major>_<minor> or master)", component.Version, component.Name)
+		}
+	case "git":
+		if !isGitRefName(component.Version) {
+			return fmt.Errorf("invalid git branch, tag or commit %q for %s", component.Version, component.Name)
+		}
+	}
+	return nil
+}
+
+// isGitURL reports whether value looks like a Git repository URL or an existing local repository
+func isGitURL(value string) bool {
+	if scpLikeGitURLPattern.MatchString(value) {
+		return true
+	}
+
+	if parsed, err := url.Parse(value); err == nil && parsed.Scheme != "" {
+		switch parsed.Scheme {
+		case "http", "https", "ssh", "git":
+			return parsed.Host != ""
+		case "file":
+			return parsed.Path != ""
+		}
+		return false
+	}
+
+	_, err := os.Stat(value)
+	return err == nil
+}
+
+// isGitRefName reports whether value is a valid Git branch or tag name, following git check-ref-format
+func isGitRefName(value string) bool {
+	if value == "" || value == "@" || strings.HasPrefix(value, "-") || strings.HasPrefix(value, "/") ||
+		strings.HasSuffix(value, "/") || strings.HasSuffix(value, ".") || strings.HasSuffix(value, ".lock") ||
+		strings.Contains(value, "..") || strings.Contains(value, "//") || strings.Contains(value, "@{") {
+		return false
+	}
+
+	for _, r := range value {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r) {
+			return false
+		}
+	}
+
+	return true
+}
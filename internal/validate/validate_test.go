@@ -0,0 +1,169 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOffline(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "test.ini")
+	content := `[mediawiki]
+version=1.43.1
+verison=1.44
+
+[extensions]
+extdist1=Cite
+extdist=Math|1.2
+extdist=Math
+git=not a url|main
+git=https://github.com/example/Math.git|bad..ref
+
+[skinz]
+foo=bar
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	cfg, problems := Offline(configPath, "")
+	if cfg == nil {
+		t.Fatal("Expected config to be loaded")
+	}
+
+	expected := []struct {
+		line    int
+		message string
+	}{
+		{3, `unknown key "verison"`},
+		{12, "unknown section [skinz]"},
+		{6, `unknown distributor "extdist1"`},
+		{7, `invalid ExtDist version "1.2"`},
+		{8, "duplicate extension Math"},
+		{9, "invalid git repository URL"},
+		{10, "invalid git branch, tag or commit"},
+		{10, "installs into Math"},
+	}
+
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+
+	for i, want := range expected {
+		if problems[i].Line != want.line || !strings.Contains(problems[i].Message, want.message) {
+			t.Errorf("Problem %d: expected line %d with %q, got %s", i, want.line, want.message, problems[i])
+		}
+	}
+}
+
+func TestOfflineStructuredLines(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"test.yaml": `mediawiki:
+  version: 1.43.1
+extensions:
+  - name: Math
+  - name: Cite
+  - name: Math
+`,
+		"test.toml": `[mediawiki]
+version = "1.43.1"
+
+[[extensions]]
+name = "Math"
+
+[[extensions]]
+name = "Math"
+`,
+	}
+
+	expected := map[string]string{
+		"test.yaml": "test.yaml:6: duplicate extension Math (first declared on line 4)",
+		"test.toml": "test.toml: duplicate extension Math",
+	}
+	for name, content := range files {
+		configPath := filepath.Join(dir, name)
+		if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		_, problems := Offline(configPath, "")
+		if len(problems) != 1 || problems[0].String() != filepath.Join(dir, expected[name]) {
+			t.Errorf("Expected %q, got %v", expected[name], problems)
+		}
+	}
+}
+
+func TestOfflineProfiles(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "test.ini")
+	content := `[mediawiki]
+version=1.43.1
+
+[extensions]
+extdist=Cite
+
+[profile:staging]
+version=1.44.0
+flavour=vanilla
+
+[profile:staging extensions]
+extdist=Math|1.2
+
+[profile:broken extensions]
+remove=Math
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	_, problems := Offline(configPath, "")
+
+	expected := []string{
+		`unknown key "flavour" in [profile:staging]`,
+		"profile broken: cannot remove Math",
+		"(profile staging)",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, want := range expected {
+		if !strings.Contains(problems[i].Message, want) {
+			t.Errorf("Problem %d: expected %q, got %s", i, want, problems[i])
+		}
+	}
+
+	// The selected profile is merged into the returned configuration
+	cfg, _ := Offline(configPath, "staging")
+	if cfg == nil || cfg.MediaWiki.Version != "1.44.0" || len(cfg.Extensions) != 2 {
+		t.Errorf("Expected the staging profile to be applied, got %+v", cfg)
+	}
+
+	if _, problems := Offline(configPath, "missing"); len(problems) != 1 {
+		t.Errorf("Expected one problem for an unknown profile, got %v", problems)
+	}
+}
+
+func TestOfflineValidConfig(t *testing.T) {
+	for _, name := range []string{"config-sample.ini", "config-sample.yaml"} {
+		if _, problems := Offline(filepath.Join("..", "..", name), ""); len(problems) > 0 {
+			t.Errorf("Expected no problems in %s, got %v", name, problems)
+		}
+	}
+}
+
+func TestIsGitRefName(t *testing.T) {
+	valid := []string{"main", "REL1_43", "v1.0.0", "feature/foo", "0123456789abcdef0123456789abcdef01234567"}
+	invalid := []string{"", "-x", "a..b", "a b", "a:b", "ends.lock", "trailing/", "a@{1}"}
+
+	for _, value := range valid {
+		if !isGitRefName(value) {
+			t.Errorf("Expected %q to be a valid ref name", value)
+		}
+	}
+	for _, value := range invalid {
+		if isGitRefName(value) {
+			t.Errorf("Expected %q to be an invalid ref name", value)
+		}
+	}
+}
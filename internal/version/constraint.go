@@ -0,0 +1,194 @@
+package version
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// orPattern separates alternatives of a constraint
+	orPattern = regexp.MustCompile(`\s*\|\|?\s*`)
+	// hyphenRangePattern matches an inclusive range like "1.39 - 1.42"
+	hyphenRangePattern = regexp.MustCompile(`^(\S+)\s+-\s+(\S+)$`)
+	// operatorSpacePattern matches whitespace between an operator and its version
+	operatorSpacePattern = regexp.MustCompile(`(>=|<=|!=|==|<>|>|<|=|\^|~)\s+`)
+	// andPattern separates the bounds of an alternative
+	andPattern = regexp.MustCompile(`\s*,\s*|\s+`)
+)
+
+// Constraint is a Composer-style version constraint as used by extension.json, skin.json and
+// composer.json, e.g. ">= 1.39.0", "^8.1", "1.43.*" or ">= 1.39 < 1.44 || >= 1.45"
+type Constraint struct {
+	raw          string
+	alternatives [][]bound // satisfied if all bounds of any alternative are satisfied
+}
+
+// bound is a single comparison like ">= 1.39.0"
+type bound struct {
+	op      string // one of >=, >, <=, <, ==, !=
+	version Version
+}
+
+// ParseConstraint parses a version constraint
+func ParseConstraint(value string) (Constraint, error) {
+	constraint := Constraint{raw: strings.TrimSpace(value)}
+
+	for _, alternative := range orPattern.Split(constraint.raw, -1) {
+		bounds, err := parseAlternative(alternative)
+		if err != nil {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q: %w", value, err)
+		}
+		constraint.alternatives = append(constraint.alternatives, bounds)
+	}
+
+	return constraint, nil
+}
+
+// String returns the constraint as it was written
+func (c Constraint) String() string {
+	return c.raw
+}
+
+// Check reports whether version satisfies the constraint
+func (c Constraint) Check(version Version) bool {
+	for _, bounds := range c.alternatives {
+		satisfied := true
+		for _, b := range bounds {
+			if !b.check(version) {
+				satisfied = false
+				break
+			}
+		}
+		if satisfied {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAlternative parses one alternative of a constraint into bounds that must all be satisfied
+func parseAlternative(alternative string) ([]bound, error) {
+	alternative = strings.TrimSpace(alternative)
+
+	if matches := hyphenRangePattern.FindStringSubmatch(alternative); matches != nil {
+		lower, err := Parse(matches[1])
+		if err != nil {
+			return nil, err
+		}
+		upper, err := Parse(matches[2])
+		if err != nil {
+			return nil, err
+		}
+
+		// A partial upper version includes everything it covers: "1.0 - 2.1" means < 2.2
+		if len(upper.Parts) < 3 {
+			return []bound{{">=", lower}, {"<", increment(upper.Parts, len(upper.Parts)-1)}}, nil
+		}
+		return []bound{{">=", lower}, {"<=", upper}}, nil
+	}
+
+	var bounds []bound
+	for _, token := range andPattern.Split(operatorSpacePattern.ReplaceAllString(alternative, "$1"), -1) {
+		parsed, err := parseBound(token)
+		if err != nil {
+			return nil, err
+		}
+		bounds = append(bounds, parsed...)
+	}
+	return bounds, nil
+}
+
+// parseBound parses a single token like ">=1.39", "^8.1", "~1.2.3" or "1.43.*"
+func parseBound(token string) ([]bound, error) {
+	if token == "" || token == "*" || token == "x" {
+		return nil, nil
+	}
+
+	for _, op := range []string{">=", "<=", "!=", "<>", "==", ">", "<", "="} {
+		if rest, found := strings.CutPrefix(token, op); found {
+			version, err := Parse(rest)
+			if err != nil {
+				return nil, err
+			}
+			switch op {
+			case "<>":
+				op = "!="
+			case "=":
+				op = "=="
+			}
+			return []bound{{op, version}}, nil
+		}
+	}
+
+	// Wildcards: 1.43.* means >= 1.43.0 < 1.44.0
+	if prefix := strings.TrimSuffix(strings.TrimSuffix(token, ".*"), ".x"); prefix != token {
+		version, err := Parse(prefix)
+		if err != nil {
+			return nil, err
+		}
+		return []bound{{">=", version}, {"<", increment(version.Parts, len(version.Parts)-1)}}, nil
+	}
+
+	// Caret: ^1.2.3 allows changes that do not modify the left-most non-zero component
+	if rest, found := strings.CutPrefix(token, "^"); found {
+		version, err := Parse(rest)
+		if err != nil {
+			return nil, err
+		}
+		index := 0
+		for index < len(version.Parts)-1 && version.Parts[index] == 0 {
+			index++
+		}
+		return []bound{{">=", version}, {"<", increment(version.Parts, index)}}, nil
+	}
+
+	// Tilde: ~1.2 means >= 1.2 < 2.0, ~1.2.3 means >= 1.2.3 < 1.3.0
+	if rest, found := strings.CutPrefix(token, "~"); found {
+		version, err := Parse(rest)
+		if err != nil {
+			return nil, err
+		}
+		return []bound{{">=", version}, {"<", increment(version.Parts, max(len(version.Parts)-2, 0))}}, nil
+	}
+
+	version, err := Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	return []bound{{"==", version}}, nil
+}
+
+// increment returns the version with the component at index incremented and the rest dropped
+func increment(parts []int, index int) Version {
+	next := make([]int, index+1)
+	copy(next, parts[:index+1])
+	next[index]++
+	return Version{Parts: next}
+}
+
+// check reports whether version satisfies the bound.
+//
+// Like Composer, ">= 1.44" includes pre-releases of 1.44.0 such as 1.44.0-alpha and
+// "< 1.45" excludes pre-releases of 1.45.0.
+func (b bound) check(version Version) bool {
+	if b.version.Pre == "" && (b.op == ">=" || b.op == "<") {
+		version = version.Release()
+	}
+
+	result := version.Compare(b.version)
+	switch b.op {
+	case ">=":
+		return result >= 0
+	case ">":
+		return result > 0
+	case "<=":
+		return result <= 0
+	case "<":
+		return result < 0
+	case "!=":
+		return result != 0
+	default:
+		return result == 0
+	}
+}
@@ -0,0 +1,132 @@
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed version number like 1.43.1 or 1.44.0-rc.0
+type Version struct {
+	// Parts are the numeric components, e.g. [1 43 1]; missing components compare as 0
+	Parts []int
+	// Pre is the pre-release suffix without the leading separator, e.g. "rc.0"
+	Pre string
+}
+
+// Parse parses a version number. A leading "v" and build metadata after "+" are ignored.
+func Parse(value string) (Version, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(value), "v")
+	trimmed, _, _ = strings.Cut(trimmed, "+")
+
+	release, pre, _ := strings.Cut(trimmed, "-")
+	if release == "" {
+		return Version{}, fmt.Errorf("invalid version %q", value)
+	}
+
+	var version Version
+	for _, part := range strings.Split(release, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", value)
+		}
+		version.Parts = append(version.Parts, number)
+	}
+	version.Pre = pre
+
+	return version, nil
+}
+
+// MustParse parses a version number and panics if it is invalid
+func MustParse(value string) Version {
+	version, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+	return version
+}
+
+// String formats the version as it was parsed, without a leading "v"
+func (v Version) String() string {
+	parts := make([]string, len(v.Parts))
+	for i, part := range v.Parts {
+		parts[i] = strconv.Itoa(part)
+	}
+
+	if v.Pre != "" {
+		return strings.Join(parts, ".") + "-" + v.Pre
+	}
+	return strings.Join(parts, ".")
+}
+
+// Release returns the version without its pre-release suffix
+func (v Version) Release() Version {
+	return Version{Parts: v.Parts}
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or higher than other.
+// A pre-release is lower than the release it precedes, e.g. 1.44.0-rc.0 < 1.44.0.
+func (v Version) Compare(other Version) int {
+	for i := range max(len(v.Parts), len(other.Parts)) {
+		a, b := v.part(i), other.part(i)
+		if a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case v.Pre == other.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case other.Pre == "":
+		return -1
+	default:
+		return comparePre(v.Pre, other.Pre)
+	}
+}
+
+// part returns the numeric component at index i, or 0 if there is none
+func (v Version) part(i int) int {
+	if i < len(v.Parts) {
+		return v.Parts[i]
+	}
+	return 0
+}
+
+// comparePre compares pre-release suffixes by their dot-separated identifiers,
+// numerically if both identifiers are numbers
+func comparePre(a, b string) int {
+	aIDs, bIDs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(aIDs), len(bIDs)) {
+		aNumber, aErr := strconv.Atoi(aIDs[i])
+		bNumber, bErr := strconv.Atoi(bIDs[i])
+
+		switch {
+		case aErr == nil && bErr == nil:
+			if aNumber != bNumber {
+				if aNumber < bNumber {
+					return -1
+				}
+				return 1
+			}
+		case aIDs[i] != bIDs[i]:
+			if aIDs[i] < bIDs[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case len(aIDs) < len(bIDs):
+		return -1
+	case len(aIDs) > len(bIDs):
+		return 1
+	default:
+		return 0
+	}
+}
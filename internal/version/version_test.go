@@ -0,0 +1,81 @@
+package version
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.43.1", "1.43.1", 0},
+		{"1.43", "1.43.0", 0},
+		{"v1.43.1", "1.43.1", 0},
+		{"1.43.1", "1.43.10", -1},
+		{"1.44.0", "1.43.9", 1},
+		{"1.44.0-rc.0", "1.44.0", -1},
+		{"1.44.0-alpha", "1.44.0-rc.0", -1},
+		{"1.44.0-rc.2", "1.44.0-rc.10", -1},
+		{"8.1.2+build", "8.1.2", 0},
+	}
+
+	for _, test := range tests {
+		if got := MustParse(test.a).Compare(MustParse(test.b)); got != test.want {
+			t.Errorf("Compare(%s, %s) = %d, expected %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, value := range []string{"", "master", "1.x.3", "REL1_43", "1..2"} {
+		if _, err := Parse(value); err == nil {
+			t.Errorf("Expected %q to be invalid", value)
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		matching   []string
+		failing    []string
+	}{
+		{">= 1.39.0", []string{"1.39.0", "1.43.1", "1.39.0-rc.0"}, []string{"1.38.4"}},
+		{">=1.35, <1.40", []string{"1.35.0", "1.39.9"}, []string{"1.40.0", "1.40.0-alpha", "1.34.0"}},
+		{">= 1.39 < 1.44 || >= 1.45", []string{"1.43.1", "1.45.0"}, []string{"1.44.0"}},
+		{"^8.1", []string{"8.1.0", "8.3.4"}, []string{"8.0.30", "9.0.0"}},
+		{"^0.3.2", []string{"0.3.5"}, []string{"0.4.0", "0.3.1"}},
+		{"~1.2", []string{"1.2.0", "1.9.9"}, []string{"2.0.0"}},
+		{"~1.2.3", []string{"1.2.9"}, []string{"1.3.0"}},
+		{"1.43.*", []string{"1.43.0", "1.43.5"}, []string{"1.44.0", "1.42.9"}},
+		{"1.39 - 1.42", []string{"1.39.0", "1.42.7"}, []string{"1.43.0"}},
+		{"*", []string{"1.0.0", "0.0.1"}, nil},
+		{"1.43.1", []string{"1.43.1"}, []string{"1.43.2"}},
+		{"!= 1.43.0", []string{"1.43.1"}, []string{"1.43.0"}},
+	}
+
+	for _, test := range tests {
+		constraint, err := ParseConstraint(test.constraint)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", test.constraint, err)
+			continue
+		}
+		for _, value := range test.matching {
+			if !constraint.Check(MustParse(value)) {
+				t.Errorf("Expected %s to satisfy %q", value, test.constraint)
+			}
+		}
+		for _, value := range test.failing {
+			if constraint.Check(MustParse(value)) {
+				t.Errorf("Expected %s not to satisfy %q", value, test.constraint)
+			}
+		}
+	}
+}
+
+func TestParseConstraintInvalid(t *testing.T) {
+	for _, value := range []string{">= master", "dev-master", "^", "1.2 - "} {
+		if _, err := ParseConstraint(value); err == nil {
+			t.Errorf("Expected %q to be invalid", value)
+		}
+	}
+}
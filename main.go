@@ -4,6 +4,9 @@ import (
 	"github.com/SKevo18/mediawiki-updater/cmd"
 )
 
+// version is set by release builds with -ldflags "-X main.version=v1.2.3"
+var version string
+
 func main() {
-	cmd.Execute()
+	cmd.Execute(version)
 }
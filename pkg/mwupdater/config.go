@@ -0,0 +1,183 @@
+package mwupdater
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+)
+
+// Component types of ComponentConfig
+const (
+	TypeExtension = "extension"
+	TypeSkin      = "skin"
+)
+
+// Config is a complete configuration, as loaded by LoadConfig or built in code
+type Config struct {
+	MediaWiki  MediaWikiConfig
+	Extensions []ComponentConfig
+	Skins      []ComponentConfig
+	Sources    SourcesConfig
+	HTTP       HTTPConfig
+}
+
+// MediaWikiConfig selects the MediaWiki version and PHP binary
+type MediaWikiConfig struct {
+	Version string
+	// SettingsFile is the generated PHP file with load statements, relative to the installation.
+	// Load statements are only generated if it is set.
+	SettingsFile string
+	// PHP is the PHP binary used to check requirements, "php" from the PATH if empty
+	PHP string
+	// Fallback are the ExtDist branches tried in order if a component has no snapshot for its branch
+	Fallback []string
+}
+
+// ComponentConfig configures an extension or skin
+type ComponentConfig struct {
+	// Type is TypeExtension or TypeSkin; it is set from the list of Config the component is in
+	Type string
+	// Distributor is "extdist" (the default), "git" or the name of one of Options.Distributors
+	Distributor string
+	// Name is the name of the component, or the repository URL for git
+	Name       string
+	Version    string
+	Dir        string   // directory name to install into, derived from Name if empty
+	Post       []string // shell commands to run in the installed directory after the update
+	PHP        []string // PHP statements written after the load statement in the settings file
+	Exclude    []string // paths relative to the component directory that are not installed
+	SHA256     string   // expected SHA-256 checksum of the downloaded archive
+	Fallback   []string // ExtDist branches to try in order, overriding the global fallback; "none" disables it
+	Required   bool     // whether a failure to download this component aborts the update
+	Submodules bool     // whether to fetch Git submodules
+	Auth       string   // credential source for Git: env:VAR, netrc[:path] or ssh:path
+}
+
+// SourcesConfig lists mirrors of the upstream endpoints, tried in order
+type SourcesConfig struct {
+	Releases   []string
+	ExtDist    []string
+	ExtDistAPI []string // "none" only uses the ExtDist directories
+	Composer   []string
+	// RateLimits override the HTTP rate limit for the hosts of a source, keyed by "releases",
+	// "extdist", "extdist_api" or "composer"
+	RateLimits map[string]float64
+}
+
+// HTTPConfig configures the HTTP client of an Updater
+type HTTPConfig struct {
+	Proxy           string
+	NoProxy         []string
+	CAFiles         []string
+	ClientCert      string
+	ClientKey       string
+	UserAgentSuffix string
+	RateLimit       float64
+	Burst           int
+	MaxConcurrent   int
+	MaxRetries      int
+}
+
+// LoadConfig loads an INI, YAML, TOML or JSON configuration file. Profiles are not part of the
+// result; use Options.ConfigPath and Options.Profile to update with one.
+func LoadConfig(path string) (*Config, error) {
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		MediaWiki: MediaWikiConfig{
+			Version:      cfg.MediaWiki.Version,
+			SettingsFile: cfg.MediaWiki.SettingsFile,
+			PHP:          cfg.MediaWiki.PHP,
+			Fallback:     slices.Clone(cfg.MediaWiki.Fallback),
+		},
+		Extensions: publicComponents(cfg.Extensions),
+		Skins:      publicComponents(cfg.Skins),
+		Sources: SourcesConfig{
+			Releases:   slices.Clone(cfg.Sources.Releases),
+			ExtDist:    slices.Clone(cfg.Sources.ExtDist),
+			ExtDistAPI: slices.Clone(cfg.Sources.ExtDistAPI),
+			Composer:   slices.Clone(cfg.Sources.Composer),
+			RateLimits: maps.Clone(cfg.Sources.RateLimits),
+		},
+		HTTP: HTTPConfig(cfg.HTTP),
+	}, nil
+}
+
+// internal converts the configuration to the one of the updater
+func (c *Config) internal() *config.Config {
+	return &config.Config{
+		MediaWiki: config.MediaWikiConfig{
+			Version:      c.MediaWiki.Version,
+			SettingsFile: c.MediaWiki.SettingsFile,
+			PHP:          c.MediaWiki.PHP,
+			Fallback:     slices.Clone(c.MediaWiki.Fallback),
+		},
+		Extensions: internalComponents(c.Extensions),
+		Skins:      internalComponents(c.Skins),
+		Sources: config.SourcesConfig{
+			Releases:   slices.Clone(c.Sources.Releases),
+			ExtDist:    slices.Clone(c.Sources.ExtDist),
+			ExtDistAPI: slices.Clone(c.Sources.ExtDistAPI),
+			Composer:   slices.Clone(c.Sources.Composer),
+			RateLimits: maps.Clone(c.Sources.RateLimits),
+		},
+		HTTP: config.HTTPConfig(c.HTTP),
+	}
+}
+
+func publicComponents(components []config.ComponentConfig) []ComponentConfig {
+	public := make([]ComponentConfig, len(components))
+	for i, component := range components {
+		public[i] = publicComponent(component)
+	}
+	return public
+}
+
+func internalComponents(components []ComponentConfig) []config.ComponentConfig {
+	converted := make([]config.ComponentConfig, len(components))
+	for i, component := range components {
+		converted[i] = component.internal()
+	}
+	return converted
+}
+
+func publicComponent(component config.ComponentConfig) ComponentConfig {
+	return ComponentConfig{
+		Type:        component.Type,
+		Distributor: component.Distributor,
+		Name:        component.Name,
+		Version:     component.Version,
+		Dir:         component.Dir,
+		Post:        slices.Clone(component.Post),
+		PHP:         slices.Clone(component.PHP),
+		Exclude:     slices.Clone(component.Exclude),
+		SHA256:      component.SHA256,
+		Fallback:    slices.Clone(component.Fallback),
+		Required:    component.Required,
+		Submodules:  component.Submodules,
+		Auth:        component.Auth,
+	}
+}
+
+// internal converts the component to the one of the updater
+func (c ComponentConfig) internal() config.ComponentConfig {
+	return config.ComponentConfig{
+		Type:        c.Type,
+		Distributor: c.Distributor,
+		Name:        c.Name,
+		Version:     c.Version,
+		Dir:         c.Dir,
+		Post:        slices.Clone(c.Post),
+		PHP:         slices.Clone(c.PHP),
+		Exclude:     slices.Clone(c.Exclude),
+		SHA256:      c.SHA256,
+		Fallback:    slices.Clone(c.Fallback),
+		Required:    c.Required,
+		Submodules:  c.Submodules,
+		Auth:        c.Auth,
+	}
+}
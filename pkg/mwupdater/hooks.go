@@ -0,0 +1,76 @@
+package mwupdater
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/SKevo18/mediawiki-updater/internal/config"
+	"github.com/SKevo18/mediawiki-updater/internal/downloader"
+)
+
+// Logger receives the messages printed while downloading and updating. *log.Logger implements it.
+// If it also has a method Debugf(format string, args ...any), it receives debug messages too.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+// HTTPClient sends HTTP requests. *http.Client implements it.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// ProgressReporter is notified of downloads as they progress. Start may be called from several
+// goroutines at once.
+type ProgressReporter interface {
+	// Start is called when the download of name begins and returns the tracker that receives its
+	// progress. size is the length of the download in bytes, or -1 if it is unknown.
+	Start(name string, size int64) ProgressTracker
+}
+
+// ProgressTracker receives the progress of a single download
+type ProgressTracker interface {
+	// Progress is called with the number of bytes downloaded so far
+	Progress(done int64)
+	// Done is called once the download has ended, with the error that ended it if it failed
+	Done(err error)
+}
+
+// Distributor fetches extensions and skins from a source other than ExtDist and Git, for
+// components whose Distributor names it in Options.Distributors
+type Distributor interface {
+	// Download installs the component into dir, the directory named after the component. The
+	// version tag is the REL branch of the MediaWiki version, like "REL1_43".
+	Download(ctx context.Context, component ComponentConfig, dir, versionTag string) (*Result, error)
+	// Resolve checks that the component can be downloaded without downloading it
+	Resolve(ctx context.Context, component ComponentConfig, versionTag string) (*Result, error)
+}
+
+// Result describes a component downloaded or resolved by a Distributor
+type Result struct {
+	Dir     string // directory the component was installed into, dir of Download if empty
+	Version string // branch, tag or version that was installed
+	Commit  string // commit SHA, empty if unknown
+	URL     string // URL the component was fetched from
+}
+
+// distributor passes the components of the updater to a Distributor
+type distributor struct {
+	distributor Distributor
+}
+
+func (d distributor) Download(ctx context.Context, component config.ComponentConfig, dir, versionTag string) (*downloader.Result, error) {
+	result, err := d.distributor.Download(ctx, publicComponent(component), dir, versionTag)
+	return internalResult(result), err
+}
+
+func (d distributor) Resolve(ctx context.Context, component config.ComponentConfig, versionTag string) (*downloader.Result, error) {
+	result, err := d.distributor.Resolve(ctx, publicComponent(component), versionTag)
+	return internalResult(result), err
+}
+
+func internalResult(result *Result) *downloader.Result {
+	if result == nil {
+		return nil
+	}
+	return &downloader.Result{Dir: result.Dir, Version: result.Version, Commit: result.Commit, URL: result.URL}
+}
@@ -0,0 +1,133 @@
+// Package mwupdater is the public Go API of mediawiki-updater: it downloads MediaWiki core,
+// extensions and skins and installs them like the command line tool does.
+//
+// The API follows semantic versioning through the tags of the module (v1.2.3): minor releases
+// only add to it, and changes that break callers bump the major version. Its types are defined
+// here rather than in the internal packages, so the implementation can change without breaking
+// callers.
+package mwupdater
+
+import (
+	"context"
+
+	"github.com/SKevo18/mediawiki-updater/internal/downloader"
+	"github.com/SKevo18/mediawiki-updater/internal/mediawiki"
+	"github.com/SKevo18/mediawiki-updater/internal/progress"
+	"github.com/SKevo18/mediawiki-updater/internal/updater"
+)
+
+// Options configures an Updater
+type Options struct {
+	// Config is the configuration to update with. If it is nil, ConfigPath is loaded instead.
+	// It is not modified. New rejects components with values LoadConfig would reject, such as a
+	// Dir or Exclude path outside the component directory.
+	Config *Config
+	// ConfigPath is an INI, YAML, TOML or JSON configuration file, used if Config is nil
+	ConfigPath string
+	// Profile is the name of a profile of ConfigPath to merge onto its base configuration
+	Profile string
+	// IgnorePaths are left untouched in the installation; LocalSettings.php, .htaccess and images if empty
+	IgnorePaths []string
+	// UseGitBinary fetches Git components with the git binary instead of the built-in implementation
+	UseGitBinary bool
+	// Strict aborts the update before anything is replaced if a component's requirements are not met
+	Strict bool
+	// WithDependencies downloads the extensions and skins required by the configured components
+	WithDependencies bool
+	// PHPBinary is the PHP binary used to check requirements, overriding the configuration
+	PHPBinary string
+	// IgnorePlatformReqs updates even if PHP, disk space or permission checks fail
+	IgnorePlatformReqs bool
+	// Bundle is a directory written by Mirror to install from instead of downloading
+	Bundle string
+
+	// Logger receives the messages the command line tool prints; nil prints them to standard output
+	Logger Logger
+	// HTTPClient sends all requests, including Git over HTTP(S), instead of a client built from
+	// the HTTP settings of the configuration
+	HTTPClient HTTPClient
+	// Progress is notified of the progress of file downloads; nil reports nothing
+	Progress ProgressReporter
+	// Distributors download the components whose Distributor names them. The built-in names
+	// "extdist" and "git" cannot be used.
+	Distributors map[string]Distributor
+}
+
+// Updater downloads MediaWiki with its extensions and skins and installs them into a directory.
+// Every Updater has its own HTTP client, logger and distributors.
+//
+// Unless UseGitBinary is set, the first Updater replaces the http and https transports of go-git,
+// which are global, with one that sends the requests of each Updater through its own client.
+// Other go-git callers in the process keep using the transports that were installed before.
+// Installing another http or https transport afterwards replaces it, and Updaters then fail to
+// fetch Git components over HTTP(S).
+type Updater struct {
+	updater *updater.Updater
+}
+
+// New creates an Updater. Run it with Update and read the outcome from Report.
+func New(opts Options) (*Updater, error) {
+	internalOpts := updater.Options{
+		ConfigPath:         opts.ConfigPath,
+		Profile:            opts.Profile,
+		IgnorePaths:        opts.IgnorePaths,
+		UseGitBinary:       opts.UseGitBinary,
+		Strict:             opts.Strict,
+		WithDependencies:   opts.WithDependencies,
+		PHPBinary:          opts.PHPBinary,
+		IgnorePlatformReqs: opts.IgnorePlatformReqs,
+		Bundle:             opts.Bundle,
+		Logger:             opts.Logger,
+		HTTPClient:         opts.HTTPClient,
+	}
+	if opts.Config != nil {
+		internalOpts.Config = opts.Config.internal()
+	}
+	if opts.Progress != nil {
+		internalOpts.Progress = progressReporter{opts.Progress}
+	}
+	if len(opts.Distributors) > 0 {
+		internalOpts.Distributors = make(map[string]downloader.Distributor, len(opts.Distributors))
+		for name, d := range opts.Distributors {
+			internalOpts.Distributors[name] = distributor{d}
+		}
+	}
+
+	u, err := updater.NewUpdater(internalOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &Updater{updater: u}, nil
+}
+
+// Update downloads MediaWiki core and the components and installs them into targetDir. If ctx
+// is cancelled before anything is copied, targetDir is left untouched. A required component
+// that cannot be downloaded fails the update with a *ComponentError.
+func (u *Updater) Update(ctx context.Context, targetDir string) error {
+	return publicError(u.updater.Update(ctx, targetDir))
+}
+
+// Mirror downloads MediaWiki core and the components into a bundle in outDir, which Options.Bundle
+// installs from without network access
+func (u *Updater) Mirror(ctx context.Context, outDir string) error {
+	return publicError(u.updater.Mirror(ctx, outDir))
+}
+
+// Report returns the report of the last Update or Mirror run
+func (u *Updater) Report() *Report {
+	return publicReport(u.updater.Report())
+}
+
+// VersionTag returns the REL branch of a MediaWiki version, e.g. "REL1_43" for "1.43.1"
+func VersionTag(version string) (string, error) {
+	return mediawiki.VersionTag(version)
+}
+
+// progressReporter passes the downloads of the updater to a ProgressReporter
+type progressReporter struct {
+	reporter ProgressReporter
+}
+
+func (r progressReporter) Start(name string, size int64) progress.Tracker {
+	return r.reporter.Start(name, size)
+}
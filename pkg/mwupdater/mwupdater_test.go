@@ -0,0 +1,178 @@
+package mwupdater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SKevo18/mediawiki-updater/internal/bundle"
+)
+
+// logger collects the logged messages
+type logger struct {
+	mu  sync.Mutex
+	out strings.Builder
+}
+
+func (l *logger) Printf(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(&l.out, format, args...)
+}
+
+// fileDistributor installs an extension.json naming the component
+type fileDistributor struct{}
+
+func (fileDistributor) Download(ctx context.Context, component ComponentConfig, dir, versionTag string) (*Result, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	manifest := fmt.Sprintf(`{"name": %q}`, component.Name)
+	if err := os.WriteFile(filepath.Join(dir, "extension.json"), []byte(manifest), 0o644); err != nil {
+		return nil, err
+	}
+	return &Result{Version: versionTag}, nil
+}
+
+func (fileDistributor) Resolve(ctx context.Context, component ComponentConfig, versionTag string) (*Result, error) {
+	return &Result{Version: versionTag}, nil
+}
+
+func TestUpdateWithConfig(t *testing.T) {
+	log := &logger{}
+	distributors := map[string]Distributor{"file": fileDistributor{}}
+
+	// Local release directory with a MediaWiki archive
+	releases := t.TempDir()
+	core := filepath.Join(t.TempDir(), "mediawiki-1.43.1")
+	if err := os.MkdirAll(core, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(core, "index.php"), []byte("<?php\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bundle.Pack(core, releases, "1.43/mediawiki-1.43.1.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		MediaWiki: MediaWikiConfig{Version: "1.43.1"},
+		Sources:   SourcesConfig{Releases: []string{releases}, ExtDistAPI: []string{"none"}},
+		Extensions: []ComponentConfig{
+			{Name: "Local", Distributor: "file"},
+			{Name: "Missing", Distributor: "nowhere", Required: true},
+		},
+	}
+	target := t.TempDir()
+	if _, err := New(Options{Config: cfg, Distributors: map[string]Distributor{"git": fileDistributor{}}}); err == nil {
+		t.Error("Expected a distributor with a built-in name to be rejected")
+	}
+
+	u, err := New(Options{Config: cfg, IgnorePlatformReqs: true, Logger: log, Distributors: distributors})
+	if err != nil {
+		t.Fatalf("Failed to create updater: %v", err)
+	}
+
+	// The required component with an unknown distributor fails the update
+	var componentErr *ComponentError
+	if err := u.Update(t.Context(), target); !errors.As(err, &componentErr) || componentErr.Name != "Missing" {
+		t.Fatalf("Expected a ComponentError for Missing, got %v", err)
+	}
+	if report := u.Report(); len(report.Components) != 2 || report.Components[0].Status != StatusInstalled {
+		t.Errorf("Unexpected report: %+v", report.Components)
+	}
+	if cfg.Extensions[0].Type != "" {
+		t.Error("Expected the configuration passed to New to be left unmodified")
+	}
+
+	cfg.Extensions = cfg.Extensions[:1]
+	cfg.Extensions[0].Post = []string{"echo hooked"}
+	u, err = New(Options{Config: cfg, IgnorePlatformReqs: true, Logger: log, Distributors: distributors})
+	if err != nil {
+		t.Fatalf("Failed to create updater: %v", err)
+	}
+	if err := u.Update(t.Context(), target); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	for _, file := range []string{"index.php", "extensions/Local/extension.json"} {
+		if _, err := os.Stat(filepath.Join(target, file)); err != nil {
+			t.Errorf("Expected %s to be installed: %v", file, err)
+		}
+	}
+	if !strings.Contains(log.out.String(), "Downloading MediaWiki core version 1.43.1") {
+		t.Errorf("Expected the messages to go to the logger, got %q", log.out.String())
+	}
+
+	// A second run of the same updater starts with a fresh report and only runs its own hooks
+	if err := u.Update(t.Context(), target); err != nil {
+		t.Fatalf("Second update failed: %v", err)
+	}
+	if report := u.Report(); len(report.Components) != 1 {
+		t.Errorf("Expected 1 component in the second report, got %+v", report.Components)
+	}
+	if runs := strings.Count(log.out.String(), "Running post-install hooks"); runs != 2 {
+		t.Errorf("Expected the hooks to run once per update, got %d runs", runs)
+	}
+}
+
+func TestUpdatersAreIndependent(t *testing.T) {
+	releases := t.TempDir()
+	core := filepath.Join(t.TempDir(), "mediawiki-1.43.1")
+	if err := os.MkdirAll(core, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(core, "index.php"), []byte("<?php\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bundle.Pack(core, releases, "1.43/mediawiki-1.43.1.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two updaters with their own loggers and distributors, created before either runs
+	first, second := &logger{}, &logger{}
+	cfg := &Config{
+		MediaWiki:  MediaWikiConfig{Version: "1.43.1"},
+		Sources:    SourcesConfig{Releases: []string{releases}, ExtDistAPI: []string{"none"}},
+		Extensions: []ComponentConfig{{Name: "Local", Distributor: "file", Required: true}},
+	}
+	a, err := New(Options{Config: cfg, IgnorePlatformReqs: true, Logger: first, Distributors: map[string]Distributor{"file": fileDistributor{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(Options{Config: cfg, IgnorePlatformReqs: true, Logger: second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Update(t.Context(), t.TempDir()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	var componentErr *ComponentError
+	if err := b.Update(t.Context(), t.TempDir()); !errors.As(err, &componentErr) {
+		t.Errorf("Expected the distributor of the first updater to be unknown to the second, got %v", err)
+	}
+
+	if strings.Count(first.out.String(), "Downloading MediaWiki core") != 1 || strings.Count(second.out.String(), "Downloading MediaWiki core") != 1 {
+		t.Errorf("Expected every logger to receive the messages of its own updater only, got %q and %q", first.out.String(), second.out.String())
+	}
+}
+
+func TestNewRejectsInvalidComponents(t *testing.T) {
+	for _, component := range []ComponentConfig{
+		{Name: "Math", Dir: "../.."},
+		{Name: "../../etc"},
+		{Name: "Math", Exclude: []string{"../../LocalSettings.php"}},
+		{Name: "Math", SHA256: "not-a-checksum"},
+		{Name: "https://example.org/repo.git", Distributor: "git", Auth: "password"},
+	} {
+		cfg := &Config{MediaWiki: MediaWikiConfig{Version: "1.43.1"}, Extensions: []ComponentConfig{component}}
+		if _, err := New(Options{Config: cfg}); err == nil {
+			t.Errorf("Expected %+v to be rejected", component)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package mwupdater
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/SKevo18/mediawiki-updater/internal/extdist"
+	"github.com/SKevo18/mediawiki-updater/internal/updater"
+)
+
+// Statuses of ComponentReport
+const (
+	StatusInstalled    = "installed"
+	StatusFailed       = "failed"
+	StatusHookFailed   = "hook failed"
+	StatusIncompatible = "incompatible" // installed, but requirements from extension.json or skin.json are not met
+)
+
+// Report records the outcome of an update
+type Report struct {
+	MediaWiki  string            `json:"mediawiki"`
+	Components []ComponentReport `json:"components"`
+}
+
+// ComponentReport records the outcome for a single extension or skin
+type ComponentReport struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Distributor string `json:"distributor"`
+	Version     string `json:"version,omitempty"`
+	// RequestedVersion is the ExtDist branch that was requested if a fallback branch was installed instead
+	RequestedVersion string `json:"requested_version,omitempty"`
+	Commit           string `json:"commit,omitempty"`
+	URL              string `json:"url,omitempty"`
+	Status           string `json:"status"`
+	Error            string `json:"error,omitempty"`
+	// Problems are the unsatisfied requirements from extension.json or skin.json
+	Problems []string `json:"problems,omitempty"`
+	// RequiredBy lists the components that caused a dependency to be added, e.g. "extension/VisualEditor"
+	RequiredBy []string `json:"required_by,omitempty"`
+}
+
+var (
+	// ErrIncompatible is returned in strict mode if components have unsatisfied requirements
+	ErrIncompatible = updater.ErrIncompatible
+	// ErrPlatform is returned if the PHP, disk space or permission checks fail
+	ErrPlatform = updater.ErrPlatform
+	// ErrNotFound is returned if ExtDist has no archive of a component
+	ErrNotFound = extdist.ErrNotFound
+)
+
+// ComponentError is returned if a required extension or skin could not be downloaded
+type ComponentError struct {
+	Type string
+	Name string
+	Err  error
+}
+
+func (e *ComponentError) Error() string {
+	return fmt.Sprintf("required %s %s could not be downloaded: %v", e.Type, e.Name, e.Err)
+}
+
+func (e *ComponentError) Unwrap() error {
+	return e.Err
+}
+
+// apiError keeps the message and chain of an error of the updater, and exposes the
+// ComponentError of the API in place of the one of the updater
+type apiError struct {
+	err       error
+	component *ComponentError
+}
+
+func (e *apiError) Error() string {
+	return e.err.Error()
+}
+
+func (e *apiError) Unwrap() []error {
+	return []error{e.component, e.err}
+}
+
+// publicError converts a ComponentError in the chain of err to the one of the API
+func publicError(err error) error {
+	var componentErr *updater.ComponentError
+	if !errors.As(err, &componentErr) {
+		return err
+	}
+	return &apiError{
+		err:       err,
+		component: &ComponentError{Type: componentErr.Type, Name: componentErr.Name, Err: componentErr.Err},
+	}
+}
+
+func publicReport(report *updater.Report) *Report {
+	public := &Report{MediaWiki: report.MediaWiki, Components: make([]ComponentReport, len(report.Components))}
+	for i, component := range report.Components {
+		public.Components[i] = ComponentReport{
+			Type:             component.Type,
+			Name:             component.Name,
+			Distributor:      component.Distributor,
+			Version:          component.Version,
+			RequestedVersion: component.RequestedVersion,
+			Commit:           component.Commit,
+			URL:              component.URL,
+			Status:           component.Status,
+			Error:            component.Error,
+			Problems:         slices.Clone(component.Problems),
+			RequiredBy:       slices.Clone(component.RequiredBy),
+		}
+	}
+	return public
+}